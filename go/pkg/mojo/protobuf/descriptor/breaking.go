@@ -0,0 +1,347 @@
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Compatibility is the set of the compatibilities broken by a change
+type Compatibility uint8
+
+const (
+	WireCompatibility   Compatibility = 1 << iota // the binary encoding and the RPC calls
+	JSONCompatibility                             // the JSON encoding
+	SourceCompatibility                           // the generated code
+
+	AllCompatibility = WireCompatibility | JSONCompatibility | SourceCompatibility
+)
+
+func (c Compatibility) String() string {
+	var levels []string
+	if c&WireCompatibility != 0 {
+		levels = append(levels, "WIRE")
+	}
+	if c&JSONCompatibility != 0 {
+		levels = append(levels, "JSON")
+	}
+	if c&SourceCompatibility != 0 {
+		levels = append(levels, "SOURCE")
+	}
+	return strings.Join(levels, "|")
+}
+
+// ChangeKind classifies the changes found by BreakingChanges
+type ChangeKind string
+
+const (
+	PackageChanged         ChangeKind = "package_changed"
+	MessageRemoved         ChangeKind = "message_removed"
+	FieldRemoved           ChangeKind = "field_removed"
+	FieldNumberReused      ChangeKind = "field_number_reused"
+	FieldNameChanged       ChangeKind = "field_name_changed"
+	FieldJsonNameChanged   ChangeKind = "field_json_name_changed"
+	FieldTypeChanged       ChangeKind = "field_type_changed"
+	FieldLabelChanged      ChangeKind = "field_label_changed"
+	EnumRemoved            ChangeKind = "enum_removed"
+	EnumValueRemoved       ChangeKind = "enum_value_removed"
+	EnumValueNameChanged   ChangeKind = "enum_value_name_changed"
+	EnumValueNumberChanged ChangeKind = "enum_value_number_changed"
+	ServiceRemoved         ChangeKind = "service_removed"
+	MethodRemoved          ChangeKind = "method_removed"
+	MethodTypeChanged      ChangeKind = "method_type_changed"
+	MethodStreamingChanged ChangeKind = "method_streaming_changed"
+)
+
+// BreakingChange is a change between two Packages which breaks the compatibilities
+type BreakingChange struct {
+	Kind     ChangeKind
+	Breaks   Compatibility
+	File     string // name of the file in which the element is declared in the previous Packages
+	FullName string // full name of the element in the previous Packages
+	Message  string
+}
+
+func (c *BreakingChange) String() string {
+	return fmt.Sprintf("%s: %s: [%s] %s", c.File, c.FullName, c.Breaks, c.Message)
+}
+
+type BreakingChanges []*BreakingChange
+
+// Filter returns the changes breaking any of the compatibilities
+func (c BreakingChanges) Filter(compatibility Compatibility) BreakingChanges {
+	var changes BreakingChanges
+	for _, change := range c {
+		if change.Breaks&compatibility != 0 {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// BreakingChanges compares the Packages with the previous one, and reports the changes breaking
+// the wire, JSON or source compatibility. The elements are matched by their full names, and the
+// moved package of a file is followed by the file path.
+func (p *Packages) BreakingChanges(previous *Packages) BreakingChanges {
+	if p == nil || previous == nil {
+		return nil
+	}
+
+	c := &breakingChecker{previous: previous, current: p}
+	for _, file := range previous.sortedFiles() {
+		c.file(file)
+	}
+	return c.changes
+}
+
+type breakingChecker struct {
+	previous *Packages
+	current  *Packages
+	changes  BreakingChanges
+}
+
+func (c *breakingChecker) report(kind ChangeKind, breaks Compatibility, d *Descriptor, fullName string, format string, args ...interface{}) {
+	c.changes = append(c.changes, &BreakingChange{
+		Kind:     kind,
+		Breaks:   breaks,
+		File:     d.File.GetName(),
+		FullName: fullName,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// translate maps the full name in the previous Packages into the current one, following the moved package
+func (c *breakingChecker) translate(fullName string) string {
+	fullName = strings.TrimPrefix(fullName, ".")
+
+	var file *File
+	if message := c.previous.GetMessage(fullName); message != nil {
+		file = message.File
+	} else if enum := c.previous.GetEnum(fullName); enum != nil {
+		file = enum.File
+	} else if service := c.previous.GetService(fullName); service != nil {
+		file = service.File
+	}
+
+	if current, ok := c.current.FilesByPath[file.GetName()]; file != nil && ok {
+		if pkg := file.GetPackageName(); pkg != current.GetPackageName() {
+			return concatFullName(current.GetPackageName(), strings.TrimPrefix(strings.TrimPrefix(fullName, pkg), "."))
+		}
+	}
+	return fullName
+}
+
+func (c *breakingChecker) file(file *File) {
+	if current, ok := c.current.FilesByPath[file.GetName()]; ok && current.GetPackageName() != file.GetPackageName() {
+		breaks := SourceCompatibility | JSONCompatibility
+		if len(file.Services) > 0 {
+			breaks |= WireCompatibility
+		}
+		c.report(PackageChanged, breaks, &Descriptor{File: file}, file.GetPackageName(),
+			"the package of the file changed from %q to %q", file.GetPackageName(), current.GetPackageName())
+	}
+
+	for _, message := range file.Messages {
+		if current := c.current.GetMessage(c.translate(message.GetFullName())); current != nil {
+			c.message(message, current)
+		} else {
+			c.report(MessageRemoved, SourceCompatibility, &message.Descriptor, message.GetFullName(), "the message was removed")
+		}
+	}
+	for _, enum := range file.Enums {
+		if current := c.current.GetEnum(c.translate(enum.GetFullName())); current != nil {
+			c.enum(enum, current)
+		} else {
+			c.report(EnumRemoved, SourceCompatibility, &enum.Descriptor, enum.GetFullName(), "the enum was removed")
+		}
+	}
+	for _, service := range file.Services {
+		if current := c.current.GetService(c.translate(service.GetFullName())); current != nil {
+			c.service(service, current)
+		} else {
+			c.report(ServiceRemoved, AllCompatibility, &service.Descriptor, service.GetFullName(), "the service was removed")
+		}
+	}
+}
+
+func (c *breakingChecker) message(previous *Message, current *Message) {
+	fields := make(map[int32]*Field)
+	for _, field := range current.Fields {
+		fields[field.GetNumber()] = field
+	}
+
+	for _, field := range previous.Fields {
+		if cur, ok := fields[field.GetNumber()]; ok {
+			c.field(field, cur)
+			continue
+		}
+
+		breaks := SourceCompatibility
+		if !current.IsReservedNumber(field.GetNumber()) {
+			breaks |= WireCompatibility
+		}
+		if !current.IsReservedName(field.GetName()) {
+			breaks |= JSONCompatibility
+		}
+		c.report(FieldRemoved, breaks, &field.Descriptor, field.GetFullName(), "the field %d was removed", field.GetNumber())
+	}
+
+	for _, msg := range previous.Messages {
+		if cur := current.GetMessage(msg.GetName()); cur != nil {
+			c.message(msg, cur)
+		} else if !msg.IsMapEntry() {
+			c.report(MessageRemoved, SourceCompatibility, &msg.Descriptor, msg.GetFullName(), "the message was removed")
+		}
+	}
+	for _, enum := range previous.Enums {
+		if cur := current.GetEnum(enum.GetName()); cur != nil {
+			c.enum(enum, cur)
+		} else {
+			c.report(EnumRemoved, SourceCompatibility, &enum.Descriptor, enum.GetFullName(), "the enum was removed")
+		}
+	}
+}
+
+func jsonName(field *Field) string {
+	if field.Proto.JsonName != nil {
+		return field.Proto.GetJsonName()
+	}
+	return JsonName(field.GetName())
+}
+
+// wireTypes groups the scalar types which are compatible in the binary encoding
+var wireTypes = map[descriptorpb.FieldDescriptorProto_Type]int{
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:    1,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32:   1,
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:    1,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64:   1,
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:     1,
+	descriptorpb.FieldDescriptorProto_TYPE_ENUM:     1,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32:   2,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64:   2,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32:  3,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32: 3,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64:  4,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64: 4,
+	descriptorpb.FieldDescriptorProto_TYPE_STRING:   5,
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES:    5,
+}
+
+func (c *breakingChecker) isTypeChanged(previous *Field, current *Field) bool {
+	if previous.Proto.GetType() != current.Proto.GetType() {
+		return true
+	}
+	if typeName := previous.Proto.GetTypeName(); len(typeName) > 0 {
+		return c.translate(typeName) != strings.TrimPrefix(current.Proto.GetTypeName(), ".")
+	}
+	return false
+}
+
+func (c *breakingChecker) field(previous *Field, current *Field) {
+	fullName := previous.GetFullName()
+	typeChanged := c.isTypeChanged(previous, current)
+
+	if previous.GetName() != current.GetName() {
+		if typeChanged {
+			c.report(FieldNumberReused, AllCompatibility, &previous.Descriptor, fullName,
+				"the field number %d was reused by the field %q with a different type", previous.GetNumber(), current.GetName())
+			return
+		}
+		breaks := SourceCompatibility
+		if jsonName(previous) != jsonName(current) {
+			breaks |= JSONCompatibility
+		}
+		c.report(FieldNameChanged, breaks, &previous.Descriptor, fullName, "the field %d was renamed to %q", previous.GetNumber(), current.GetName())
+	} else if jsonName(previous) != jsonName(current) {
+		c.report(FieldJsonNameChanged, JSONCompatibility, &previous.Descriptor, fullName,
+			"the json name changed from %q to %q", jsonName(previous), jsonName(current))
+	}
+
+	if typeChanged {
+		breaks := AllCompatibility
+		group, ok := wireTypes[previous.Proto.GetType()]
+		if ok && group == wireTypes[current.Proto.GetType()] {
+			breaks = JSONCompatibility | SourceCompatibility
+		}
+		c.report(FieldTypeChanged, breaks, &previous.Descriptor, fullName, "the type changed from %q to %q", fieldTypeName(previous), fieldTypeName(current))
+	}
+
+	previousLabel, currentLabel := previous.Proto.GetLabel(), current.Proto.GetLabel()
+	switch {
+	case previous.IsRepeated() != current.IsRepeated():
+		c.report(FieldLabelChanged, AllCompatibility, &previous.Descriptor, fullName, "the label changed from %q to %q", labelName(previousLabel), labelName(currentLabel))
+	case previousLabel != currentLabel:
+		c.report(FieldLabelChanged, WireCompatibility|SourceCompatibility, &previous.Descriptor, fullName, "the label changed from %q to %q", labelName(previousLabel), labelName(currentLabel))
+	case previous.Proto.GetProto3Optional() != current.Proto.GetProto3Optional():
+		c.report(FieldLabelChanged, SourceCompatibility, &previous.Descriptor, fullName, "the presence of the field changed")
+	}
+}
+
+func fieldTypeName(field *Field) string {
+	if typeName := field.Proto.GetTypeName(); len(typeName) > 0 {
+		return strings.TrimPrefix(typeName, ".")
+	}
+	return strings.ToLower(strings.TrimPrefix(field.Proto.GetType().String(), "TYPE_"))
+}
+
+func labelName(label descriptorpb.FieldDescriptorProto_Label) string {
+	return strings.ToLower(strings.TrimPrefix(label.String(), "LABEL_"))
+}
+
+func (c *breakingChecker) enum(previous *Enum, current *Enum) {
+	numbers := make(map[int32]*EnumValue)
+	for _, value := range current.Values {
+		if _, ok := numbers[value.GetNumber()]; !ok {
+			numbers[value.GetNumber()] = value
+		}
+	}
+
+	for _, value := range previous.Values {
+		fullName := enumValueFullName(previous, value)
+		if cur := current.GetValue(value.GetName()); cur != nil {
+			if cur.GetNumber() != value.GetNumber() {
+				c.report(EnumValueNumberChanged, WireCompatibility, &value.Descriptor, fullName,
+					"the number changed from %d to %d", value.GetNumber(), cur.GetNumber())
+			}
+		} else if cur = numbers[value.GetNumber()]; cur != nil {
+			c.report(EnumValueNameChanged, JSONCompatibility|SourceCompatibility, &value.Descriptor, fullName,
+				"the enum value %d was renamed to %q", value.GetNumber(), cur.GetName())
+		} else {
+			breaks := SourceCompatibility
+			if !current.IsReservedNumber(value.GetNumber()) {
+				breaks |= WireCompatibility
+			}
+			if !current.IsReservedName(value.GetName()) {
+				breaks |= JSONCompatibility
+			}
+			c.report(EnumValueRemoved, breaks, &value.Descriptor, fullName, "the enum value %d was removed", value.GetNumber())
+		}
+	}
+}
+
+func (c *breakingChecker) service(previous *Service, current *Service) {
+	for _, method := range previous.Methods {
+		fullName := concatFullName(previous.GetFullName(), method.GetName())
+		cur := current.GetMethod(method.GetName())
+		if cur == nil {
+			c.report(MethodRemoved, AllCompatibility, &method.Descriptor, fullName, "the method was removed")
+			continue
+		}
+
+		if c.translate(method.Proto.GetInputType()) != strings.TrimPrefix(cur.Proto.GetInputType(), ".") {
+			c.report(MethodTypeChanged, AllCompatibility, &method.Descriptor, fullName,
+				"the input type changed from %q to %q", method.Proto.GetInputType(), cur.Proto.GetInputType())
+		}
+		if c.translate(method.Proto.GetOutputType()) != strings.TrimPrefix(cur.Proto.GetOutputType(), ".") {
+			c.report(MethodTypeChanged, AllCompatibility, &method.Descriptor, fullName,
+				"the output type changed from %q to %q", method.Proto.GetOutputType(), cur.Proto.GetOutputType())
+		}
+		if method.Proto.GetClientStreaming() != cur.Proto.GetClientStreaming() {
+			c.report(MethodStreamingChanged, AllCompatibility, &method.Descriptor, fullName, "the client streaming changed")
+		}
+		if method.Proto.GetServerStreaming() != cur.Proto.GetServerStreaming() {
+			c.report(MethodStreamingChanged, AllCompatibility, &method.Descriptor, fullName, "the server streaming changed")
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func newBreakingTestFileProto() *descriptorpb.FileDescriptorProto {
+	fd := newTestFileProto()
+	fd.MessageType[0].Field = []*descriptorpb.FieldDescriptorProto{{
+		Name:   proto.String("name"),
+		Number: proto.Int32(1),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+	}, {
+		Name:   proto.String("count"),
+		Number: proto.Int32(2),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+	}, {
+		Name:     proto.String("kind"),
+		Number:   proto.Int32(3),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+		TypeName: proto.String(".foo.Outer.Inner.Kind"),
+	}, {
+		Name:   proto.String("tags"),
+		Number: proto.Int32(4),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+	}}
+	fd.MessageType[0].NestedType[0].EnumType[0].Value = []*descriptorpb.EnumValueDescriptorProto{
+		{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+		{Name: proto.String("KIND_ONE"), Number: proto.Int32(1)},
+		{Name: proto.String("KIND_TWO"), Number: proto.Int32(2)},
+	}
+	return fd
+}
+
+func newBreakingTestPackages(fd *descriptorpb.FileDescriptorProto) *Packages {
+	return NewPackages().AddFile(NewFileFrom(fd))
+}
+
+func TestPackages_BreakingChanges(t *testing.T) {
+	previous := newBreakingTestPackages(newBreakingTestFileProto())
+	assert.Empty(t, newBreakingTestPackages(newBreakingTestFileProto()).BreakingChanges(previous))
+
+	fd := newBreakingTestFileProto()
+	outer := fd.MessageType[0]
+	outer.Field[0].Type = descriptorpb.FieldDescriptorProto_TYPE_BYTES.Enum()
+	outer.Field[1].Name = proto.String("total")
+	outer.Field[1].Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	outer.Field[2].JsonName = proto.String("type")
+	outer.Field[3].Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	enum := outer.NestedType[0].EnumType[0]
+	enum.Value = enum.Value[:2]
+	enum.ReservedName = []string{"KIND_TWO"}
+	fd.Service[0].Method[0].ServerStreaming = proto.Bool(true)
+
+	changes := newBreakingTestPackages(fd).BreakingChanges(previous)
+	if assert.Len(t, changes, 6) {
+		assert.Equal(t, FieldTypeChanged, changes[0].Kind)
+		assert.Equal(t, "foo.Outer.name", changes[0].FullName)
+		assert.Equal(t, JSONCompatibility|SourceCompatibility, changes[0].Breaks)
+
+		assert.Equal(t, FieldNumberReused, changes[1].Kind)
+		assert.Equal(t, AllCompatibility, changes[1].Breaks)
+
+		assert.Equal(t, FieldJsonNameChanged, changes[2].Kind)
+		assert.Equal(t, JSONCompatibility, changes[2].Breaks)
+
+		assert.Equal(t, FieldLabelChanged, changes[3].Kind)
+		assert.Equal(t, "WIRE|JSON|SOURCE", changes[3].Breaks.String())
+
+		assert.Equal(t, EnumValueRemoved, changes[4].Kind)
+		assert.Equal(t, "foo.Outer.Inner.KIND_TWO", changes[4].FullName)
+		assert.Equal(t, WireCompatibility|SourceCompatibility, changes[4].Breaks)
+
+		assert.Equal(t, MethodStreamingChanged, changes[5].Kind)
+		assert.Equal(t, "foo.FooService.Get", changes[5].FullName)
+	}
+	assert.Len(t, changes.Filter(WireCompatibility), 4)
+}
+
+func TestPackages_BreakingChanges_Removed(t *testing.T) {
+	previous := newBreakingTestPackages(newBreakingTestFileProto())
+
+	fd := newBreakingTestFileProto()
+	outer := fd.MessageType[0]
+	outer.Field = outer.Field[1:]
+	outer.ReservedRange = []*descriptorpb.DescriptorProto_ReservedRange{{Start: proto.Int32(1), End: proto.Int32(2)}}
+	outer.ReservedName = []string{"name"}
+	outer.NestedType[0].EnumType = nil
+	outer.Field[1].Type = descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()
+	outer.Field[1].TypeName = nil
+	fd.Service = nil
+
+	changes := newBreakingTestPackages(fd).BreakingChanges(previous)
+	if assert.Len(t, changes, 4) {
+		assert.Equal(t, FieldRemoved, changes[0].Kind)
+		assert.Equal(t, SourceCompatibility, changes[0].Breaks)
+		assert.Equal(t, FieldTypeChanged, changes[1].Kind)
+		assert.Equal(t, JSONCompatibility|SourceCompatibility, changes[1].Breaks)
+		assert.Equal(t, EnumRemoved, changes[2].Kind)
+		assert.Equal(t, ServiceRemoved, changes[3].Kind)
+		assert.Equal(t, "foo/foo.proto", changes[3].File)
+	}
+}
+
+func TestPackages_BreakingChanges_PackageMoved(t *testing.T) {
+	previous := newBreakingTestPackages(newBreakingTestFileProto())
+
+	fd := newBreakingTestFileProto()
+	fd.Package = proto.String("bar")
+	fd.MessageType[0].Field[2].TypeName = proto.String(".bar.Outer.Inner.Kind")
+	fd.Service[0].Method[0].InputType = proto.String(".bar.Outer.Inner")
+	fd.Service[0].Method[0].OutputType = proto.String(".bar.Outer")
+
+	changes := newBreakingTestPackages(fd).BreakingChanges(previous)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, PackageChanged, changes[0].Kind)
+		assert.Equal(t, AllCompatibility, changes[0].Breaks)
+	}
+}
@@ -30,6 +30,37 @@ func (d *Descriptor) TrailingComments() Comments {
     return ""
 }
 
+func (d *Descriptor) SetLeadingComments(comments string) *Descriptor {
+    if d != nil {
+        d.Comments.Leading = Comments(comments)
+    }
+    return d
+}
+
+func (d *Descriptor) SetTrailingComments(comments string) *Descriptor {
+    if d != nil {
+        d.Comments.Trailing = Comments(comments)
+    }
+    return d
+}
+
+func (d *Descriptor) SetLeadingDetachedComments(comments ...string) *Descriptor {
+    if d != nil {
+        d.Comments.LeadingDetached = nil
+        d.AppendLeadingDetachedComments(comments...)
+    }
+    return d
+}
+
+func (d *Descriptor) AppendLeadingDetachedComments(comments ...string) *Descriptor {
+    if d != nil {
+        for _, c := range comments {
+            d.Comments.LeadingDetached = append(d.Comments.LeadingDetached, Comments(c))
+        }
+    }
+    return d
+}
+
 // AppendPath add elements to a Location's path, returning a new Location.
 func (d *Descriptor) AppendPath(num protoreflect.FieldNumber, idx int) *Descriptor {
     d.Path = append(protoreflect.SourcePath(nil), d.Path...) // make copy
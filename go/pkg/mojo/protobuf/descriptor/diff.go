@@ -0,0 +1,406 @@
+package descriptor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DiffKind is the kind of a node in the Diff
+type DiffKind string
+
+const (
+	Added    DiffKind = "added"
+	Removed  DiffKind = "removed"
+	Modified DiffKind = "modified"
+)
+
+// DiffNode is an added or removed element, or a modified property of an element
+type DiffNode struct {
+	Kind     DiffKind
+	Element  string // the kind of the element, "file", "message", "field", "oneof", "enum", "enum value", "extension", "service" or "method"
+	FullName string // the full name of the element, or the file name for the file element
+	Property string // the modified property, like "json_name", "options.deprecated" or "options.(mojo.alias)"
+
+	// Before and After are the descriptor protos for the removed and added elements, and the values
+	// of the modified property. The enum values are the names, the lists are []interface{} and
+	// the messages are proto.Message. The value is nil if the property is not set.
+	Before interface{}
+	After  interface{}
+}
+
+func (n *DiffNode) String() string {
+	switch n.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s %s", n.Element, n.FullName)
+	case Removed:
+		return fmt.Sprintf("- %s %s", n.Element, n.FullName)
+	default:
+		return fmt.Sprintf("~ %s %s %s: %s -> %s", n.Element, n.FullName, n.Property, formatDiffValue(n.Before), formatDiffValue(n.After))
+	}
+}
+
+func formatDiffValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<unset>"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("%q", v)
+	case proto.Message:
+		return formatDiffMessage(v.ProtoReflect())
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			values = append(values, formatDiffValue(e))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formatDiffMessage formats the message in the text format on a single line, the fields are ordered by the
+// numbers and followed by the extensions, which is stable unlike the output of prototext.
+func formatDiffMessage(m protoreflect.Message) string {
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, field)
+		return true
+	})
+	sortFieldDescriptors(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		name := string(field.Name())
+		if field.IsExtension() {
+			name = "[" + string(field.FullName()) + "]"
+		}
+
+		value := m.Get(field)
+		switch {
+		case field.IsList():
+			list := value.List()
+			values := make([]string, 0, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				values = append(values, formatDiffField(field, list.Get(i)))
+			}
+			parts = append(parts, name+": ["+strings.Join(values, ", ")+"]")
+		case field.IsMap():
+			var entries []string
+			value.Map().Range(func(key protoreflect.MapKey, v protoreflect.Value) bool {
+				entries = append(entries, name+" {key: "+formatDiffField(field.MapKey(), key.Value())+" value: "+formatDiffField(field.MapValue(), v)+"}")
+				return true
+			})
+			sort.Strings(entries)
+			parts = append(parts, entries...)
+		default:
+			parts = append(parts, name+": "+formatDiffField(field, value))
+		}
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+func formatDiffField(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return formatDiffMessage(value.Message())
+	default:
+		return formatValue(field, value, "", 0)
+	}
+}
+
+// Diff is the structural difference between two descriptor trees. The nodes of an element are ordered
+// as its modified properties first, then its children, the removed and modified ones in the order of
+// the previous tree, then the added ones.
+type Diff []*DiffNode
+
+// String renders the Diff one node per line
+func (d Diff) String() string {
+	var buffer strings.Builder
+	for _, node := range d {
+		buffer.WriteString(node.String())
+		buffer.WriteByte('\n')
+	}
+	return buffer.String()
+}
+
+// Filter returns the nodes of the kind
+func (d Diff) Filter(kind DiffKind) Diff {
+	var nodes Diff
+	for _, node := range d {
+		if node.Kind == kind {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Diff compares the file with the other one, the elements are matched by their names, and
+// all the properties and options are compared except the source code info.
+func (f *File) Diff(other *File) Diff {
+	d := &differ{}
+	d.element("file", f.GetName(), f.GetPackageName(), other.GetPackageName(), f.getProto(), other.getProto())
+	return d.nodes
+}
+
+func (f *File) getProto() proto.Message {
+	if f != nil && f.Proto != nil {
+		return f.Proto
+	}
+	return nil
+}
+
+// Diff compares the message with the other one, including all their fields and nested types
+func (m *Message) Diff(other *Message) Diff {
+	d := &differ{}
+	d.element("message", m.GetFullName(), m.GetFullName(), other.GetFullName(), m.getProto(), other.getProto())
+	return d.nodes
+}
+
+func (m *Message) getProto() proto.Message {
+	if m != nil && m.Proto != nil {
+		return m.Proto
+	}
+	return nil
+}
+
+// elementFields are the fields in the descriptor protos holding the child elements
+var elementFields = map[protoreflect.FullName]string{
+	"google.protobuf.FileDescriptorProto.message_type": "message",
+	"google.protobuf.FileDescriptorProto.enum_type":    "enum",
+	"google.protobuf.FileDescriptorProto.service":      "service",
+	"google.protobuf.FileDescriptorProto.extension":    "extension",
+	"google.protobuf.DescriptorProto.field":            "field",
+	"google.protobuf.DescriptorProto.nested_type":      "message",
+	"google.protobuf.DescriptorProto.enum_type":        "enum",
+	"google.protobuf.DescriptorProto.extension":        "extension",
+	"google.protobuf.DescriptorProto.oneof_decl":       "oneof",
+	"google.protobuf.EnumDescriptorProto.value":        "enum value",
+	"google.protobuf.ServiceDescriptorProto.method":    "method",
+}
+
+type differ struct {
+	nodes Diff
+}
+
+// element compares the descriptor protos of the element, the before and after scopes are
+// the names used to build the full names of the children
+func (d *differ) element(element string, fullName string, beforeScope string, afterScope string, before proto.Message, after proto.Message) {
+	if before == nil || after == nil {
+		if before != nil {
+			d.nodes = append(d.nodes, &DiffNode{Kind: Removed, Element: element, FullName: fullName, Before: before})
+		} else if after != nil {
+			d.nodes = append(d.nodes, &DiffNode{Kind: Added, Element: element, FullName: fullName, After: after})
+		}
+		return
+	}
+
+	b, a := before.ProtoReflect(), after.ProtoReflect()
+	var children []protoreflect.FieldDescriptor
+	fields := b.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Name() == "source_code_info" {
+			continue
+		}
+		if _, ok := elementFields[field.FullName()]; ok {
+			children = append(children, field)
+			continue
+		}
+		if field.Name() == "options" {
+			d.options(element, fullName, b.Get(field).Message(), a.Get(field).Message())
+			continue
+		}
+		if !b.Has(field) && !a.Has(field) || b.Has(field) && a.Has(field) && valueEqual(field, b.Get(field), a.Get(field)) {
+			continue
+		}
+		d.nodes = append(d.nodes, &DiffNode{
+			Kind:     Modified,
+			Element:  element,
+			FullName: fullName,
+			Property: string(field.Name()),
+			Before:   diffValue(field, b),
+			After:    diffValue(field, a),
+		})
+	}
+
+	for _, field := range children {
+		d.children(elementFields[field.FullName()], beforeScope, afterScope, b.Get(field).List(), a.Get(field).List())
+	}
+}
+
+func (d *differ) children(element string, beforeScope string, afterScope string, before protoreflect.List, after protoreflect.List) {
+	names := make(map[string]proto.Message)
+	for i := 0; i < after.Len(); i++ {
+		child := after.Get(i).Message().Interface()
+		if name := elementName(child); len(name) > 0 {
+			if _, ok := names[name]; !ok {
+				names[name] = child
+			}
+		}
+	}
+
+	matched := make(map[string]bool)
+	for i := 0; i < before.Len(); i++ {
+		child := before.Get(i).Message().Interface()
+		name := elementName(child)
+		if matched[name] {
+			continue
+		}
+		matched[name] = true
+		fullName := concatFullName(beforeScope, name)
+		if element == "enum" {
+			// the enum values are the siblings of the enum
+			d.element(element, fullName, beforeScope, afterScope, child, names[name])
+		} else {
+			d.element(element, fullName, fullName, concatFullName(afterScope, name), child, names[name])
+		}
+	}
+
+	for i := 0; i < after.Len(); i++ {
+		child := after.Get(i).Message().Interface()
+		name := elementName(child)
+		if !matched[name] {
+			matched[name] = true
+			d.element(element, concatFullName(afterScope, name), "", "", nil, child)
+		}
+	}
+}
+
+func elementName(element proto.Message) string {
+	m := element.ProtoReflect()
+	if field := m.Descriptor().Fields().ByName("name"); field != nil {
+		return m.Get(field).String()
+	}
+	return ""
+}
+
+// options compares all the options including the extensions, ordered by the field numbers
+func (d *differ) options(element string, fullName string, before protoreflect.Message, after protoreflect.Message) {
+	fields := make(map[protoreflect.FieldNumber]protoreflect.FieldDescriptor)
+	collect := func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields[field.Number()] = field
+		return true
+	}
+	if before.IsValid() {
+		before.Range(collect)
+	}
+	if after.IsValid() {
+		after.Range(collect)
+	}
+
+	numbers := make([]protoreflect.FieldNumber, 0, len(fields))
+	for number := range fields {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	for _, number := range numbers {
+		field := fields[number]
+		hasBefore := before.IsValid() && before.Has(field)
+		hasAfter := after.IsValid() && after.Has(field)
+		if hasBefore && hasAfter && valueEqual(field, before.Get(field), after.Get(field)) {
+			continue
+		}
+
+		name := string(field.Name())
+		if field.IsExtension() {
+			name = "(" + string(field.FullName()) + ")"
+		}
+		node := &DiffNode{Kind: Modified, Element: element, FullName: fullName, Property: "options." + name}
+		if hasBefore {
+			node.Before = diffValue(field, before)
+		}
+		if hasAfter {
+			node.After = diffValue(field, after)
+		}
+		d.nodes = append(d.nodes, node)
+	}
+}
+
+func valueEqual(field protoreflect.FieldDescriptor, a protoreflect.Value, b protoreflect.Value) bool {
+	switch {
+	case field.IsList():
+		l1, l2 := a.List(), b.List()
+		if l1.Len() != l2.Len() {
+			return false
+		}
+		for i := 0; i < l1.Len(); i++ {
+			if !singularEqual(field, l1.Get(i), l2.Get(i)) {
+				return false
+			}
+		}
+		return true
+	case field.IsMap():
+		m1, m2 := a.Map(), b.Map()
+		if m1.Len() != m2.Len() {
+			return false
+		}
+		equal := true
+		m1.Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			equal = m2.Has(key) && singularEqual(field.MapValue(), value, m2.Get(key))
+			return equal
+		})
+		return equal
+	default:
+		return singularEqual(field, a, b)
+	}
+}
+
+func singularEqual(field protoreflect.FieldDescriptor, a protoreflect.Value, b protoreflect.Value) bool {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return proto.Equal(a.Message().Interface(), b.Message().Interface())
+	case protoreflect.BytesKind:
+		return bytes.Equal(a.Bytes(), b.Bytes())
+	default:
+		return a.Interface() == b.Interface()
+	}
+}
+
+// diffValue returns the value of the field in the message, or nil if not set
+func diffValue(field protoreflect.FieldDescriptor, m protoreflect.Message) interface{} {
+	if !m.Has(field) {
+		return nil
+	}
+
+	value := m.Get(field)
+	switch {
+	case field.IsList():
+		list := value.List()
+		values := make([]interface{}, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			values = append(values, singularValue(field, list.Get(i)))
+		}
+		return values
+	case field.IsMap():
+		values := make(map[interface{}]interface{})
+		value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			values[key.Interface()] = singularValue(field.MapValue(), value)
+			return true
+		})
+		return values
+	default:
+		return singularValue(field, value)
+	}
+}
+
+func singularValue(field protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		if v := field.Enum().Values().ByNumber(value.Enum()); v != nil {
+			return string(v.Name())
+		}
+		return int32(value.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return value.Message().Interface()
+	default:
+		return value.Interface()
+	}
+}
@@ -0,0 +1,86 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFile_Diff(t *testing.T) {
+	before := NewFileFrom(newBreakingTestFileProto())
+	assert.Empty(t, before.Diff(NewFileFrom(newBreakingTestFileProto())))
+
+	fd := newBreakingTestFileProto()
+	fd.Options = &descriptorpb.FileOptions{GoPackage: proto.String("github.com/foo/foo")}
+	outer := fd.MessageType[0]
+	outer.Field[0].JsonName = proto.String("title")
+	outer.Field[1].Options = &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+	outer.Field[3].Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	outer.Field = append(outer.Field, &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String("id"),
+		Number: proto.Int32(5),
+		Type:   descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+	})
+	enum := outer.NestedType[0].EnumType[0]
+	enum.Value = enum.Value[:2]
+	fd.Service = nil
+
+	diff := before.Diff(NewFileFrom(fd))
+	assert.Equal(t, `~ file foo/foo.proto options.go_package: <unset> -> "github.com/foo/foo"
+~ field foo.Outer.name json_name: <unset> -> "title"
+~ field foo.Outer.count options.deprecated: <unset> -> true
+~ field foo.Outer.tags label: "LABEL_REPEATED" -> "LABEL_OPTIONAL"
++ field foo.Outer.id
+- enum value foo.Outer.Inner.KIND_TWO
+- service foo.FooService
+`, diff.String())
+
+	if added := diff.Filter(Added); assert.Len(t, added, 1) {
+		assert.Equal(t, "field", added[0].Element)
+		assert.Equal(t, int32(5), added[0].After.(*descriptorpb.FieldDescriptorProto).GetNumber())
+	}
+}
+
+func TestMessage_Diff(t *testing.T) {
+	before := NewFileFrom(newBreakingTestFileProto()).GetMessage("Outer")
+
+	fd := newBreakingTestFileProto()
+	fd.MessageType[0].ReservedName = []string{"old"}
+	fd.MessageType[0].NestedType[0].Name = proto.String("Nested")
+	after := NewFileFrom(fd).GetMessage("Outer")
+
+	diff := before.Diff(after)
+	if assert.Len(t, diff, 3) {
+		assert.Equal(t, Modified, diff[0].Kind)
+		assert.Equal(t, "reserved_name", diff[0].Property)
+		assert.Nil(t, diff[0].Before)
+		assert.Equal(t, []interface{}{"old"}, diff[0].After)
+
+		assert.Equal(t, "- message foo.Outer.Inner", diff[1].String())
+		assert.Equal(t, "+ message foo.Outer.Nested", diff[2].String())
+	}
+}
+
+func TestDiff_String_Message(t *testing.T) {
+	before := NewFileFrom(newBreakingTestFileProto()).GetMessage("Outer")
+
+	fd := newBreakingTestFileProto()
+	fd.MessageType[0].ReservedRange = []*descriptorpb.DescriptorProto_ReservedRange{
+		{Start: proto.Int32(5), End: proto.Int32(6)},
+		{Start: proto.Int32(8), End: proto.Int32(10)},
+	}
+	fd.MessageType[0].Field[0].Options = &descriptorpb.FieldOptions{
+		UninterpretedOption: []*descriptorpb.UninterpretedOption{{
+			Name:            []*descriptorpb.UninterpretedOption_NamePart{{NamePart: proto.String("foo"), IsExtension: proto.Bool(true)}},
+			AggregateValue:  proto.String("a: 1"),
+			IdentifierValue: proto.String("x"),
+		}},
+	}
+
+	diff := before.Diff(NewFileFrom(fd).GetMessage("Outer"))
+	assert.Equal(t, `~ message foo.Outer reserved_range: <unset> -> [{start: 5 end: 6}, {start: 8 end: 10}]
+~ field foo.Outer.name options.uninterpreted_option: <unset> -> [{name: [{name_part: "foo" is_extension: true}] identifier_value: "x" aggregate_value: "a: 1"}]
+`, diff.String())
+}
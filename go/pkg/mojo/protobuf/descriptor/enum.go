@@ -1,8 +1,8 @@
 package descriptor
 
 import (
+    "google.golang.org/protobuf/reflect/protoreflect"
     "google.golang.org/protobuf/types/descriptorpb"
-    "strings"
 )
 
 // Enum describes an enum. If it's at top level, its Parent will be nil.
@@ -37,7 +37,9 @@ func NewEnumFrom(file *File, proto *descriptorpb.EnumDescriptorProto) *Enum {
         Proto: proto,
     }
 
-    for _, value := range proto.Value {
+    values := proto.Value
+    proto.Value = nil
+    for _, value := range values {
         enum.AppendValue(NewEnumValueFrom(enum, value))
     }
 
@@ -77,13 +79,21 @@ func (m *Enum) GetName() string {
 func (m *Enum) GetFullName() string {
     if m != nil {
         if len(m.FullName) == 0 {
-            m.FullName = strings.Join([]string{m.GetPackageName(), m.GetName()}, ".")
+            m.updateFullName()
         }
         return m.FullName
     }
     return ""
 }
 
+func (m *Enum) updateFullName() {
+    if m.Parent != nil {
+        m.FullName = concatFullName(m.Parent.GetFullName(), m.GetName())
+    } else {
+        m.FullName = concatFullName(m.GetPackageName(), m.GetName())
+    }
+}
+
 func (m *Enum) GetPackageName() string {
     if m != nil && m.File != nil {
         return m.File.GetPackageName()
@@ -94,7 +104,7 @@ func (m *Enum) GetPackageName() string {
 func (m *Enum) SetName(name string) *Enum {
     if m != nil && m.Proto != nil {
         m.Proto.Name = &name
-        m.FullName = concatFullName(m.File.GetPackageName(), name)
+        m.updateFullName()
     }
     return m
 }
@@ -115,3 +125,50 @@ func (m *Enum) GetValue(name string) *EnumValue {
 func (m *Enum) IsValueExist(name string) bool {
     return m.GetValue(name) != nil
 }
+
+func (m *Enum) HasOption(extension protoreflect.ExtensionType) bool {
+    return hasOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Enum) GetOption(extension protoreflect.ExtensionType) interface{} {
+    return getOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Enum) GetBoolOption(extension protoreflect.ExtensionType) bool {
+    return getBoolOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Enum) GetInt64Option(extension protoreflect.ExtensionType) int64 {
+    return getInt64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *Enum) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
+    return getFloat64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *Enum) GetStringOption(extension protoreflect.ExtensionType) string {
+    return getStringOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Enum) SetOption(extension protoreflect.ExtensionType, value interface{}) *Enum {
+    if m != nil && m.Proto != nil {
+        setOption(&m.Proto.Options, extension, value)
+    }
+    return m
+}
+
+func (m *Enum) SetBoolOption(extension protoreflect.ExtensionType, value bool) *Enum {
+    return m.SetOption(extension, value)
+}
+
+func (m *Enum) SetStringOption(extension protoreflect.ExtensionType, value string) *Enum {
+    return m.SetOption(extension, value)
+}
+
+func (m *Enum) SetInt64Option(extension protoreflect.ExtensionType, value int64) *Enum {
+    return m.SetOption(extension, value)
+}
+
+func (m *Enum) SetFloat64Option(extension protoreflect.ExtensionType, value float64) *Enum {
+    return m.SetOption(extension, value)
+}
@@ -1,6 +1,9 @@
 package descriptor
 
-import "google.golang.org/protobuf/types/descriptorpb"
+import (
+    "google.golang.org/protobuf/reflect/protoreflect"
+    "google.golang.org/protobuf/types/descriptorpb"
+)
 
 type EnumValue struct {
     Descriptor
@@ -50,3 +53,50 @@ func (m *EnumValue) GetName() string {
 func (m *EnumValue) GetNumber() int32 {
     return m.proto().GetNumber()
 }
+
+func (m *EnumValue) HasOption(extension protoreflect.ExtensionType) bool {
+    return hasOption(m.proto().GetOptions(), extension)
+}
+
+func (m *EnumValue) GetOption(extension protoreflect.ExtensionType) interface{} {
+    return getOption(m.proto().GetOptions(), extension)
+}
+
+func (m *EnumValue) GetBoolOption(extension protoreflect.ExtensionType) bool {
+    return getBoolOption(m.proto().GetOptions(), extension)
+}
+
+func (m *EnumValue) GetInt64Option(extension protoreflect.ExtensionType) int64 {
+    return getInt64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *EnumValue) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
+    return getFloat64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *EnumValue) GetStringOption(extension protoreflect.ExtensionType) string {
+    return getStringOption(m.proto().GetOptions(), extension)
+}
+
+func (m *EnumValue) SetOption(extension protoreflect.ExtensionType, value interface{}) *EnumValue {
+    if m != nil && m.Proto != nil {
+        setOption(&m.Proto.Options, extension, value)
+    }
+    return m
+}
+
+func (m *EnumValue) SetBoolOption(extension protoreflect.ExtensionType, value bool) *EnumValue {
+    return m.SetOption(extension, value)
+}
+
+func (m *EnumValue) SetStringOption(extension protoreflect.ExtensionType, value string) *EnumValue {
+    return m.SetOption(extension, value)
+}
+
+func (m *EnumValue) SetInt64Option(extension protoreflect.ExtensionType, value int64) *EnumValue {
+    return m.SetOption(extension, value)
+}
+
+func (m *EnumValue) SetFloat64Option(extension protoreflect.ExtensionType, value float64) *EnumValue {
+    return m.SetOption(extension, value)
+}
@@ -1,6 +1,8 @@
 package descriptor
 
 import (
+	"strings"
+
 	"github.com/mojo-lang/core/go/pkg/mojo/core"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -8,17 +10,57 @@ import (
 )
 
 const (
-	FieldTypeBool   = "bool"
-	FieldTypeInt32  = "int32"
-	FieldTypeUInt32 = "uint32"
-	FieldTypeInt64  = "int64"
-	FieldTypeUInt64 = "uint64"
-	FieldTypeFloat  = "float"
-	FieldTypeDouble = "double"
-	FieldTypeString = "string"
-	FieldTypeBytes  = "bytes"
+	FieldTypeBool     = "bool"
+	FieldTypeInt32    = "int32"
+	FieldTypeUInt32   = "uint32"
+	FieldTypeInt64    = "int64"
+	FieldTypeUInt64   = "uint64"
+	FieldTypeSInt32   = "sint32"
+	FieldTypeSInt64   = "sint64"
+	FieldTypeFixed32  = "fixed32"
+	FieldTypeFixed64  = "fixed64"
+	FieldTypeSFixed32 = "sfixed32"
+	FieldTypeSFixed64 = "sfixed64"
+	FieldTypeFloat    = "float"
+	FieldTypeDouble   = "double"
+	FieldTypeString   = "string"
+	FieldTypeBytes    = "bytes"
+	FieldTypeGroup    = "group"
 )
 
+// FieldEncoding is the hint of the wire encoding for the integer types
+type FieldEncoding int
+
+const (
+	VarintEncoding FieldEncoding = iota // the default encoding, int32, uint32, int64 or uint64
+	ZigZagEncoding                      // the zigzag varint encoding for the signed integers, sint32 or sint64
+	FixedEncoding                       // the fixed-width encoding, fixed32, fixed64, sfixed32 or sfixed64
+)
+
+// ParseFieldEncoding parses the encoding hint, which is "varint", "zigzag" or "fixed"
+func ParseFieldEncoding(encoding string) (FieldEncoding, bool) {
+	switch strings.ToLower(encoding) {
+	case "", "varint":
+		return VarintEncoding, true
+	case "zigzag":
+		return ZigZagEncoding, true
+	case "fixed":
+		return FixedEncoding, true
+	}
+	return VarintEncoding, false
+}
+
+func (e FieldEncoding) String() string {
+	switch e {
+	case ZigZagEncoding:
+		return "zigzag"
+	case FixedEncoding:
+		return "fixed"
+	default:
+		return "varint"
+	}
+}
+
 // A Field describes a message field.
 type Field struct {
 	Descriptor
@@ -30,6 +72,8 @@ type Field struct {
 
 	Enum    *Enum    // type for enum fields; nil otherwise
 	Message *Message // type for message or group fields; nil otherwise
+
+	Extendee *Message // extended message for extension fields, resolved by Packages.Link; nil otherwise
 }
 
 var (
@@ -82,6 +126,53 @@ func NewMessageField(parent *Message, name string, message *Message) *Field {
 	return field
 }
 
+// NewMapField creates the map field with the key and value types, the map entry message is synthesized
+// and nested in the parent as what protoc does when the field is appended. The value type may be a scalar
+// type or the name of a message or enum type, which will be resolved by Packages.Link.
+func NewMapField(parent *Message, name string, keyType string, valueType string) *Field {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	entry := NewMessage(parent.File)
+	entry.Parent = parent
+	entry.SetName(MapEntryName(name)).SetMapEntry(true)
+	key := NewField(entry, "key").SetType(keyType).SetNumber(1)
+	key.Proto.Label = &optional
+	entry.AppendField(key)
+
+	value := NewField(entry, "value").SetNumber(2)
+	value.Proto.Label = &optional
+	if typ := protoType(valueType); typ == messageType {
+		value.SetTypeName(valueType)
+	} else {
+		value.Proto.Type = &typ
+	}
+	entry.AppendField(value)
+
+	field := NewMessageField(parent, name, entry)
+	field.Proto.Label = &repeated
+	field.Proto.TypeName = proto.String("." + entry.GetFullName())
+	return field
+}
+
+// MapEntryName returns the name of the map entry message as what protoc does, e.g. "foo_bar" -> "FooBarEntry"
+func MapEntryName(fieldName string) string {
+	b := &strings.Builder{}
+	upper := true
+	for i := 0; i < len(fieldName); i++ {
+		c := fieldName[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteByte(c)
+	}
+	b.WriteString("Entry")
+	return b.String()
+}
+
 func NewFieldFrom(parent *Message, proto *descriptorpb.FieldDescriptorProto) *Field {
 	field := &Field{
 		Descriptor: Descriptor{
@@ -93,6 +184,30 @@ func NewFieldFrom(parent *Message, proto *descriptorpb.FieldDescriptorProto) *Fi
 	return field
 }
 
+// NewExtension creates the top-level extension of the extendee, which is the type name of the extended message
+func NewExtension(file *File, extendee string, name string) *Field {
+	return &Field{
+		Descriptor: Descriptor{
+			File: file,
+		},
+		Proto: &descriptorpb.FieldDescriptorProto{
+			Name:     &name,
+			Extendee: &extendee,
+		},
+	}
+}
+
+// NewExtensionFrom wraps the extension declared in the scope message, or the top-level extension if the scope is nil
+func NewExtensionFrom(file *File, scope *Message, proto *descriptorpb.FieldDescriptorProto) *Field {
+	return &Field{
+		Descriptor: Descriptor{
+			File: file,
+		},
+		Proto:  proto,
+		Parent: scope,
+	}
+}
+
 func (m *Field) proto() *descriptorpb.FieldDescriptorProto {
 	if m != nil {
 		return m.Proto
@@ -112,17 +227,64 @@ func (m *Field) GetName() string {
 	return m.proto().GetName()
 }
 
+// GetFullName returns the full name of the field, which is the full name of the message followed by the field name
+func (m *Field) GetFullName() string {
+	if m != nil {
+		return concatFullName(m.scope(), m.GetName())
+	}
+	return ""
+}
+
+// scope is the full name of the message in which the field is declared, or the package for the top-level extension
+func (m *Field) scope() string {
+	if m.Parent != nil {
+		return m.Parent.GetFullName()
+	}
+	return m.File.GetPackageName()
+}
+
+func (m *Field) IsExtension() bool {
+	return m.proto().Extendee != nil
+}
+
+// GetExtendee returns the type name of the message extended by the extension field
+func (m *Field) GetExtendee() string {
+	return m.proto().GetExtendee()
+}
+
+func (m *Field) SetExtendee(extendee string) *Field {
+	if m != nil && m.Proto != nil {
+		m.Proto.Extendee = &extendee
+	}
+	return m
+}
+
 func (m *Field) GetNumber() int32 {
 	return m.proto().GetNumber()
 }
 
 func (m *Field) GetTypeName() string {
-	if name, ok := fieldDescriptorProtoTypeName[*m.Proto.Type]; ok {
-		return name
+	if fd := m.proto(); fd != nil && fd.Type != nil {
+		if name, ok := fieldDescriptorProtoTypeName[fd.GetType()]; ok {
+			return name
+		}
 	}
 	return m.proto().GetTypeName()
 }
 
+// GetEncoding returns the wire encoding of the integer field, VarintEncoding for the other types
+func (m *Field) GetEncoding() FieldEncoding {
+	switch m.proto().GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SINT64:
+		return ZigZagEncoding
+	case descriptorpb.FieldDescriptorProto_TYPE_FIXED32, descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return FixedEncoding
+	default:
+		return VarintEncoding
+	}
+}
+
 // GetOptions get the predefined options in the field options
 func (m *Field) GetOptions() *descriptorpb.FieldOptions {
 	return m.proto().GetOptions()
@@ -133,7 +295,7 @@ func (m *Field) HasOptions() bool {
 }
 
 func (m *Field) HasOption(extension protoreflect.ExtensionType) bool {
-	return proto.HasExtension(m.Proto.Options, extension)
+	return hasOption(m.proto().GetOptions(), extension)
 }
 
 func (m *Field) HasExtension(extension protoreflect.ExtensionType) bool {
@@ -141,48 +303,29 @@ func (m *Field) HasExtension(extension protoreflect.ExtensionType) bool {
 }
 
 func (m *Field) GetBoolOption(extension protoreflect.ExtensionType) bool {
-	if v, ok := m.GetOption(extension).(bool); ok {
-		return v
-	}
-	return false
+	return getBoolOption(m.proto().GetOptions(), extension)
 }
 
 func (m *Field) GetInt64Option(extension protoreflect.ExtensionType) int64 {
-	if v, ok := m.GetOption(extension).(int64); ok {
-		return v
-	}
-	return 0
+	return getInt64Option(m.proto().GetOptions(), extension)
 }
 
 func (m *Field) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
-	if v, ok := m.GetOption(extension).(float64); ok {
-		return v
-	}
-	return 0
+	return getFloat64Option(m.proto().GetOptions(), extension)
 }
 
 func (m *Field) GetStringOption(extension protoreflect.ExtensionType) string {
-	if v, ok := m.GetOption(extension).(string); ok {
-		return v
-	}
-	return ""
+	return getStringOption(m.proto().GetOptions(), extension)
 }
 
 func (m *Field) GetOption(extension protoreflect.ExtensionType) interface{} {
-	if m.Proto.GetOptions() != nil {
-		return proto.GetExtension(m.Proto.Options, extension)
-	}
-
-	return nil
+	return getOption(m.proto().GetOptions(), extension)
 }
 
 func (m *Field) SetOption(extension protoreflect.ExtensionType, value interface{}) *Field {
-	if !m.HasExtension(extension) {
-		if m.Proto.Options == nil {
-			m.Proto.Options = &descriptorpb.FieldOptions{}
-		}
+	if m != nil && m.Proto != nil {
+		setOption(&m.Proto.Options, extension, value)
 	}
-	proto.SetExtension(m.Proto.Options, extension, value)
 	return m
 }
 
@@ -202,6 +345,47 @@ func (m *Field) SetFloat64Option(extension protoreflect.ExtensionType, value flo
 	return m.SetOption(extension, value)
 }
 
+// IsMap checks whether the field is a map, which is a repeated field of the nested map entry message
+func (m *Field) IsMap() bool {
+	return m.mapEntry() != nil
+}
+
+// MapKey returns the key field of the map entry, nil if the field is not a map
+func (m *Field) MapKey() *Field {
+	return m.mapEntry().GetFieldByNumber(1)
+}
+
+// MapValue returns the value field of the map entry, nil if the field is not a map
+func (m *Field) MapValue() *Field {
+	return m.mapEntry().GetFieldByNumber(2)
+}
+
+func (m *Field) mapEntry() *Message {
+	if m == nil || m.Proto == nil {
+		return nil
+	}
+	return mapEntryOf(m.Parent, m.Message, m.Proto)
+}
+
+// mapEntryOf returns the map entry of the field declared in the scope, the entry is looked up in the
+// nested messages of the scope if not linked
+func mapEntryOf(scope *Message, entry *Message, fd *descriptorpb.FieldDescriptorProto) *Message {
+	if fd.GetType() != messageType || fd.GetLabel() != repeated {
+		return nil
+	}
+	if entry == nil && scope != nil {
+		name := fd.GetTypeName()
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		entry = scope.GetMessage(name)
+	}
+	if entry.IsMapEntry() {
+		return entry
+	}
+	return nil
+}
+
 func (m *Field) IsRepeated() bool {
 	return m.proto().GetLabel() == repeated
 }
@@ -235,6 +419,20 @@ func (m *Field) SetType(t string) *Field {
 	return m
 }
 
+// SetTypeWithEncoding sets the integer type with the encoding hint, e.g. Int32 with ZigZagEncoding is sint32,
+// UInt64 with FixedEncoding is fixed64. The hint only applies to the mojo integer types, it's ignored by the
+// protobuf scalar type names, the other types, and by the unsigned types with ZigZagEncoding.
+func (m *Field) SetTypeWithEncoding(t string, encoding FieldEncoding) *Field {
+	if m != nil && m.Proto != nil {
+		typ := protoType(t)
+		if fieldDescriptorProtoTypeName[typ] != t {
+			typ = encodedProtoType(typ, encoding)
+		}
+		m.Proto.Type = &typ
+	}
+	return m
+}
+
 func (m *Field) SetTypeName(name string) *Field {
 	if m != nil && m.Proto != nil {
 		name = protoTypeName(name)
@@ -244,18 +442,67 @@ func (m *Field) SetTypeName(name string) *Field {
 }
 
 var fieldDescriptorProtoTypeName = map[descriptorpb.FieldDescriptorProto_Type]string{
-	descriptorpb.FieldDescriptorProto_TYPE_BOOL:   FieldTypeBool,
-	descriptorpb.FieldDescriptorProto_TYPE_INT32:  FieldTypeInt32,
-	descriptorpb.FieldDescriptorProto_TYPE_UINT32: FieldTypeUInt32,
-	descriptorpb.FieldDescriptorProto_TYPE_INT64:  FieldTypeInt64,
-	descriptorpb.FieldDescriptorProto_TYPE_UINT64: FieldTypeUInt64,
-	descriptorpb.FieldDescriptorProto_TYPE_FLOAT:  FieldTypeFloat,
-	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE: FieldTypeDouble,
-	descriptorpb.FieldDescriptorProto_TYPE_STRING: FieldTypeString,
-	descriptorpb.FieldDescriptorProto_TYPE_BYTES:  FieldTypeBytes,
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:     FieldTypeBool,
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:    FieldTypeInt32,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32:   FieldTypeUInt32,
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:    FieldTypeInt64,
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64:   FieldTypeUInt64,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32:   FieldTypeSInt32,
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64:   FieldTypeSInt64,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32:  FieldTypeFixed32,
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64:  FieldTypeFixed64,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32: FieldTypeSFixed32,
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64: FieldTypeSFixed64,
+	descriptorpb.FieldDescriptorProto_TYPE_FLOAT:    FieldTypeFloat,
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:   FieldTypeDouble,
+	descriptorpb.FieldDescriptorProto_TYPE_STRING:   FieldTypeString,
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES:    FieldTypeBytes,
+}
+
+// encodedProtoType returns the integer type with the encoding
+func encodedProtoType(t descriptorpb.FieldDescriptorProto_Type, encoding FieldEncoding) descriptorpb.FieldDescriptorProto_Type {
+	switch encoding {
+	case ZigZagEncoding:
+		switch t {
+		case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+			return descriptorpb.FieldDescriptorProto_TYPE_SINT32
+		case descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+			return descriptorpb.FieldDescriptorProto_TYPE_SINT64
+		}
+	case FixedEncoding:
+		switch t {
+		case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_SINT32:
+			return descriptorpb.FieldDescriptorProto_TYPE_SFIXED32
+		case descriptorpb.FieldDescriptorProto_TYPE_INT64, descriptorpb.FieldDescriptorProto_TYPE_SINT64:
+			return descriptorpb.FieldDescriptorProto_TYPE_SFIXED64
+		case descriptorpb.FieldDescriptorProto_TYPE_UINT32:
+			return descriptorpb.FieldDescriptorProto_TYPE_FIXED32
+		case descriptorpb.FieldDescriptorProto_TYPE_UINT64:
+			return descriptorpb.FieldDescriptorProto_TYPE_FIXED64
+		}
+	case VarintEncoding:
+		switch t {
+		case descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+			return descriptorpb.FieldDescriptorProto_TYPE_INT32
+		case descriptorpb.FieldDescriptorProto_TYPE_SINT64, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+			return descriptorpb.FieldDescriptorProto_TYPE_INT64
+		case descriptorpb.FieldDescriptorProto_TYPE_FIXED32:
+			return descriptorpb.FieldDescriptorProto_TYPE_UINT32
+		case descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+			return descriptorpb.FieldDescriptorProto_TYPE_UINT64
+		}
+	}
+	return t
 }
 
+// protoType returns the type of the mojo type or the protobuf scalar type name
 func protoType(t string) descriptorpb.FieldDescriptorProto_Type {
+	for typ, name := range fieldDescriptorProtoTypeName {
+		if name == t {
+			return typ
+		}
+	}
+
 	switch t {
 	case core.DoubleTypeName, core.Float64TypeName, core.DoubleTypeFullName, core.Float64TypeFullName:
 		return descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
@@ -277,6 +524,8 @@ func protoType(t string) descriptorpb.FieldDescriptorProto_Type {
 		return descriptorpb.FieldDescriptorProto_TYPE_BYTES
 	case "Enum":
 		return descriptorpb.FieldDescriptorProto_TYPE_ENUM
+	case FieldTypeGroup:
+		return descriptorpb.FieldDescriptorProto_TYPE_GROUP
 	default:
 		return descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
 	}
@@ -1 +1,137 @@
 package descriptor
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// fieldNumberAllocator picks the available field numbers of the message, the used numbers of the
+// previous version of the message are also excluded in the stable mode.
+type fieldNumberAllocator struct {
+	message  *Message
+	previous *Message
+
+	used     map[int32]bool
+	excluded []numberRange
+	next     int32
+}
+
+func newFieldNumberAllocator(message *Message, previous *Message) *fieldNumberAllocator {
+	a := &fieldNumberAllocator{
+		message:  message,
+		previous: previous,
+		used:     make(map[int32]bool),
+		excluded: []numberRange{{start: reservedFieldNumberStart, end: reservedFieldNumberEnd}},
+	}
+
+	for _, m := range []*Message{message, previous} {
+		for _, field := range m.GetFields() {
+			if number := field.GetNumber(); number > 0 {
+				a.used[number] = true
+				if number > a.next {
+					a.next = number
+				}
+			}
+		}
+		for _, r := range m.proto().GetReservedRange() {
+			a.excluded = append(a.excluded, numberRange{start: r.GetStart(), end: r.GetEnd() - 1})
+		}
+		for _, r := range m.proto().GetExtensionRange() {
+			a.excluded = append(a.excluded, numberRange{start: r.GetStart(), end: r.GetEnd() - 1})
+		}
+	}
+	a.excluded = mergeRanges(a.excluded)
+	return a
+}
+
+// search returns the first available number from the start, or 0 if all the numbers are unavailable
+func (a *fieldNumberAllocator) search(start int32) int32 {
+	for number := start; number > 0 && number <= MaxFieldNumber; {
+		skipped := false
+		for _, r := range a.excluded {
+			if number >= r.start && number <= r.end {
+				number = r.end + 1
+				skipped = true
+			}
+		}
+		if skipped {
+			continue
+		}
+		if !a.used[number] {
+			return number
+		}
+		number++
+	}
+	return 0
+}
+
+// allocate returns the number after the largest used one, or the first unused one if the largest
+// number has been reached.
+func (a *fieldNumberAllocator) allocate() int32 {
+	number := a.search(a.next + 1)
+	if number == 0 {
+		number = a.search(1)
+	}
+	if number > 0 {
+		a.used[number] = true
+		if number > a.next {
+			a.next = number
+		}
+	}
+	return number
+}
+
+// reuse returns the number of the field with the same name in the previous version of the message,
+// or 0 if the field is not found or its number has been used by the other field.
+func (a *fieldNumberAllocator) reuse(name string) int32 {
+	field := a.previous.GetField(name)
+	if field == nil || field.GetNumber() <= 0 || a.message.IsReservedNumber(field.GetNumber()) || a.message.IsExtensionNumber(field.GetNumber()) {
+		return 0
+	}
+	for _, f := range a.message.Fields {
+		if f.GetNumber() == field.GetNumber() {
+			return 0
+		}
+	}
+	return field.GetNumber()
+}
+
+// NextFieldNumber returns the next available field number after the largest one used by the fields,
+// skipping the reserved numbers, the extension ranges and the numbers 19000 through 19999.
+// The first unused number is returned if the largest field number has been reached, and 0 if there is no
+// available number at all.
+func (m *Message) NextFieldNumber() int32 {
+	if m == nil {
+		return 0
+	}
+	return newFieldNumberAllocator(m, nil).allocate()
+}
+
+// AllocateFieldNumbers assigns the numbers to the fields without the numbers in the declaration order.
+//
+// In the stable mode where the previous version of the message is not nil, the fields will reuse the
+// numbers of the fields with the same names in the previous message, and the numbers of the
+// previous fields will not be assigned to the new fields.
+func (m *Message) AllocateFieldNumbers(previous *Message) *Message {
+	if m == nil || m.Proto == nil {
+		return m
+	}
+
+	a := newFieldNumberAllocator(m, previous)
+	if previous != nil {
+		for _, field := range m.Fields {
+			if field.GetNumber() == 0 {
+				if number := a.reuse(field.GetName()); number > 0 {
+					field.Proto.Number = proto.Int32(number)
+				}
+			}
+		}
+	}
+	for _, field := range m.Fields {
+		if field.GetNumber() == 0 {
+			if number := a.allocate(); number > 0 {
+				field.Proto.Number = proto.Int32(number)
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,48 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage_NextFieldNumber(t *testing.T) {
+	message := NewMessage(NewFile()).SetName("Foo")
+	assert.Equal(t, int32(1), message.NextFieldNumber())
+
+	message.AppendField(NewField(message, "name").SetType("String").SetNumber(1))
+	message.AddReservedRange(2, 4).AddExtensionRange(5, 9)
+	assert.Equal(t, int32(10), message.NextFieldNumber())
+
+	message.AppendField(NewField(message, "id").SetType("String").SetNumber(18999))
+	assert.Equal(t, int32(20000), message.NextFieldNumber())
+
+	message.AddExtensionRange(20000, MaxFieldNumber)
+	assert.Equal(t, int32(10), message.NextFieldNumber())
+}
+
+func TestMessage_AllocateFieldNumbers(t *testing.T) {
+	previous := NewMessage(NewFile()).SetName("Foo")
+	previous.AppendField(NewField(previous, "name").SetType("String").SetNumber(1))
+	previous.AppendField(NewField(previous, "removed").SetType("String").SetNumber(2))
+	previous.AppendField(NewField(previous, "id").SetType("String").SetNumber(3))
+
+	message := NewMessage(NewFile()).SetName("Foo")
+	message.AppendField(NewField(message, "id").SetType("String"))
+	message.AppendField(NewField(message, "value").SetType("String"))
+	message.AppendField(NewField(message, "name").SetType("String"))
+	message.AppendField(NewField(message, "kind").SetType("String").SetNumber(7))
+	message.AddReservedNumbers(8)
+
+	message.AllocateFieldNumbers(previous)
+	assert.Equal(t, int32(3), message.GetField("id").GetNumber())
+	assert.Equal(t, int32(9), message.GetField("value").GetNumber())
+	assert.Equal(t, int32(1), message.GetField("name").GetNumber())
+
+	message = NewMessage(NewFile()).SetName("Foo")
+	message.AppendField(NewField(message, "id").SetType("String"))
+	message.AppendField(NewField(message, "name").SetType("String"))
+	message.AllocateFieldNumbers(nil)
+	assert.Equal(t, int32(1), message.GetField("id").GetNumber())
+	assert.Equal(t, int32(2), message.GetField("name").GetNumber())
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/mojo-lang/core/go/pkg/mojo"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func newField() *Field {
@@ -48,3 +50,144 @@ func TestField_SetNumber(t *testing.T) {
 	field.SetNumber(14)
 	assert.Equal(t, int32(14), field.GetNumber())
 }
+
+func TestNewMapField(t *testing.T) {
+	file := NewFileWithName("foo/foo.proto", "foo")
+	message := NewMessage(file).SetName("Foo")
+	file.AppendMessage(message)
+	bar := NewMessage(file).SetName("Bar")
+	file.AppendMessage(bar)
+
+	NewMapField(message, "dropped", "String", "String")
+	assert.Empty(t, message.Messages)
+
+	labels := NewMapField(message, "label_values", "String", "String").SetNumber(1)
+	assert.Equal(t, ".foo.Foo.LabelValuesEntry", labels.Proto.GetTypeName())
+	message.AppendField(labels)
+	bars := NewMapField(message, "bars", "Int64", "Bar").SetNumber(2)
+	message.AppendField(bars)
+
+	assert.True(t, labels.IsMap())
+	assert.Equal(t, "LabelValuesEntry", labels.Message.GetName())
+	assert.Equal(t, []*Message{labels.Message, bars.Message}, message.Messages)
+	assert.Equal(t, "key", labels.MapKey().GetName())
+	assert.Equal(t, "string", labels.MapValue().GetTypeName())
+	assert.False(t, NewField(message, "name").SetType("String").IsMap())
+
+	packages := NewPackages().AddFile(file)
+	assert.NoError(t, packages.Link())
+	assert.Equal(t, bar, bars.MapValue().Message)
+	assert.Empty(t, file.Validate())
+
+	assert.Contains(t, message.PrintToString(), "map<string, string> label_values = 1;")
+	assert.Contains(t, message.PrintToString(), "map<int64, Bar> bars = 2;")
+	assert.NotContains(t, message.PrintToString(), "LabelValuesEntry")
+
+	_, err := file.ToFileDescriptor()
+	assert.NoError(t, err)
+}
+
+func TestField_SetOptional(t *testing.T) {
+	file := NewFileWithName("foo.proto", "foo")
+	message := NewMessage(file).SetName("Foo")
+	message.AppendOneofWith("kind")
+	message.AppendField(NewField(message, "bar").SetType(FieldTypeString).SetNumber(1))
+	message.GetLastOneof().AppendField(message.Fields[0])
+	message.Fields[0].Proto.OneofIndex = proto.Int32(0)
+
+	field := NewField(message, "baz").SetType(FieldTypeInt32).SetNumber(2)
+	message.AppendField(field)
+	field.SetOptional(true)
+	assert.True(t, field.IsOptional())
+	assert.True(t, field.Oneof.IsSynthetic())
+	assert.Equal(t, "_baz", field.Oneof.GetName())
+	assert.Equal(t, int32(1), field.Proto.GetOneofIndex())
+
+	message.AppendOneofWith("value")
+	assert.Equal(t, []string{"kind", "value", "_baz"}, []string{message.Oneofs[0].GetName(), message.Oneofs[1].GetName(), message.Oneofs[2].GetName()})
+	assert.Equal(t, int32(2), field.Proto.GetOneofIndex())
+	assert.Equal(t, "value", message.GetLastOneof().GetName())
+	assert.Len(t, message.GetRealOneofs(), 2)
+	assert.Len(t, message.GetSyntheticOneofs(), 1)
+
+	message.Fields[0].SetOptional(true)
+	assert.False(t, message.Fields[0].IsOptional())
+
+	field.SetOptional(false)
+	assert.False(t, field.IsOptional())
+	assert.Nil(t, field.Oneof)
+	assert.Nil(t, field.Proto.OneofIndex)
+	assert.Len(t, message.Oneofs, 2)
+	assert.Empty(t, message.GetSyntheticOneofs())
+}
+
+func TestField_SetOptional_Proto2(t *testing.T) {
+	file := NewFileFrom(&descriptorpb.FileDescriptorProto{Name: proto.String("foo.proto"), Package: proto.String("foo")})
+	message := NewMessage(file).SetName("Foo")
+	file.AppendMessage(message)
+	field := NewField(message, "bar").SetType(FieldTypeString).SetNumber(1)
+	message.AppendField(field)
+
+	field.SetOptional(true)
+	assert.True(t, field.IsOptional())
+	assert.False(t, field.Proto.GetProto3Optional())
+	assert.Empty(t, message.Oneofs)
+
+	_, err := file.ToFileDescriptor()
+	assert.NoError(t, err)
+}
+
+func TestField_SetTypeWithEncoding(t *testing.T) {
+	field := newField().SetTypeWithEncoding("Int32", ZigZagEncoding)
+	assert.Equal(t, FieldTypeSInt32, field.GetTypeName())
+	assert.Equal(t, ZigZagEncoding, field.GetEncoding())
+
+	field.SetTypeWithEncoding("UInt64", FixedEncoding)
+	assert.Equal(t, FieldTypeFixed64, field.GetTypeName())
+
+	field.SetTypeWithEncoding("Int64", FixedEncoding)
+	assert.Equal(t, FieldTypeSFixed64, field.GetTypeName())
+	assert.Equal(t, FixedEncoding, field.GetEncoding())
+
+	field.SetTypeWithEncoding("UInt32", ZigZagEncoding)
+	assert.Equal(t, FieldTypeUInt32, field.GetTypeName())
+	assert.Equal(t, VarintEncoding, field.GetEncoding())
+
+	field.SetType(FieldTypeSFixed32)
+	assert.Equal(t, FieldTypeSFixed32, field.GetTypeName())
+
+	field.SetTypeWithEncoding(FieldTypeSInt32, VarintEncoding)
+	assert.Equal(t, FieldTypeSInt32, field.GetTypeName())
+	field.SetTypeWithEncoding(FieldTypeSFixed32, ZigZagEncoding)
+	assert.Equal(t, FieldTypeSFixed32, field.GetTypeName())
+	field.SetTypeWithEncoding(FieldTypeInt64, FixedEncoding)
+	assert.Equal(t, FieldTypeInt64, field.GetTypeName())
+
+	encoding, ok := ParseFieldEncoding("ZigZag")
+	assert.True(t, ok)
+	assert.Equal(t, ZigZagEncoding, encoding)
+	_, ok = ParseFieldEncoding("packed")
+	assert.False(t, ok)
+}
+
+func TestField_GetMojoTypeName(t *testing.T) {
+	file := NewFileWithName("foo.proto", "foo")
+	message := NewMessage(file).SetName("Foo")
+	file.AppendMessage(message)
+
+	field := NewField(message, "count").SetType(FieldTypeSInt64)
+	assert.Equal(t, "Int64", field.GetMojoTypeName(nil))
+	assert.Equal(t, "Array<Int64>", field.SetRepeated().GetMojoTypeName(nil))
+
+	field = NewMessageField(message, "created", nil).SetTypeName(".google.protobuf.Timestamp")
+	assert.Equal(t, "Timestamp", field.GetMojoTypeName(nil))
+	field = NewMessageField(message, "name", nil).SetTypeName(".google.protobuf.StringValue")
+	assert.Equal(t, "StringValue", field.GetMojoTypeName(nil))
+
+	field = NewMapField(message, "labels", "String", ".foo.Bar")
+	assert.Equal(t, "Map<String, foo.Bar>", field.GetMojoTypeName(nil))
+
+	mapping := NewMojoTypeMapping().SetType(".foo.Bar", "Baz").SetScalar(descriptorpb.FieldDescriptorProto_TYPE_STRING, "Text")
+	assert.Equal(t, "Map<Text, Baz>", field.GetMojoTypeName(mapping))
+	assert.Equal(t, "foo.Bar", NewMessageField(message, "bar", nil).SetTypeName(".foo.Bar").GetMojoTypeName(nil))
+}
@@ -11,6 +11,8 @@ import (
 var (
 	Proto2Syntax = "proto2"
 	Proto3Syntax = "proto3"
+
+	EditionsSyntax = "editions"
 )
 
 // File describes a protocol buffer descriptor File (.proto).
@@ -21,9 +23,10 @@ type File struct {
 
 	Proto *descriptorpb.FileDescriptorProto
 
-	Messages []*Message // All the Messages defined in this File.
-	Enums    []*Enum    // All the Enums defined in this File.
-	Services []*Service // All the top-level extensions defined in this File.
+	Messages   []*Message // All the Messages defined in this File.
+	Enums      []*Enum    // All the Enums defined in this File.
+	Services   []*Service // All the Services defined in this File.
+	Extensions []*Field   // All the top-level extensions defined in this File.
 
 	cursor protoreflect.SourcePath
 }
@@ -64,6 +67,11 @@ func NewFileFrom(proto *descriptorpb.FileDescriptorProto) *File {
 		file.Services = append(file.Services, s)
 	}
 
+	for _, extension := range proto.Extension {
+		file.Extensions = append(file.Extensions, NewExtensionFrom(file, nil, extension))
+	}
+
+	file.ExtractComments()
 	return file
 }
 
@@ -155,6 +163,14 @@ func (f *File) SetProto3(value bool) *File {
 	return f
 }
 
+// GetSyntax returns the syntax of the file, the empty syntax is treated as proto2 as protoc does
+func (f *File) GetSyntax() string {
+	if syntax := f.proto().GetSyntax(); len(syntax) > 0 {
+		return syntax
+	}
+	return Proto2Syntax
+}
+
 func (f *File) GetName() string {
 	return f.proto().GetName()
 }
@@ -234,8 +250,24 @@ func (f *File) IsServiceExist(name string) bool {
 	return f.GetService(name) != nil
 }
 
+func (f *File) HasExtension() bool {
+	return f != nil && len(f.Extensions) > 0
+}
+
+// GetExtension get the top-level extension by the name
+func (f *File) GetExtension(name string) *Field {
+	if f != nil {
+		for _, e := range f.Extensions {
+			if name == e.GetName() {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
 func (f *File) IsEmpty() bool {
-	return !(f.HasMessage() || f.HasEnum() || f.HasService())
+	return !(f.HasMessage() || f.HasEnum() || f.HasService() || f.HasExtension())
 }
 
 func (f *File) AppendMessage(message *Message) *File {
@@ -262,6 +294,17 @@ func (f *File) AppendService(service *Service) *File {
 	return f
 }
 
+// AppendExtension appends the top-level extension, the extendee of which should be set
+func (f *File) AppendExtension(extension *Field) *File {
+	if f != nil && f.Proto != nil {
+		extension.File = f
+		extension.Parent = nil
+		f.Extensions = append(f.Extensions, extension)
+		f.proto().Extension = append(f.proto().Extension, extension.Proto)
+	}
+	return f
+}
+
 func (f *File) GetDependencies() []string {
 	return f.proto().GetDependency()
 }
@@ -287,20 +330,6 @@ func (f *File) CleanDependency() *File {
 	return f
 }
 
-func (f *File) ExtractComments() {
-	// file.Comments = make(map[string]*descriptorpb.SourceCodeInfo_Location)
-	// for _, loc := range file.GetSourceCodeInfo().GetLocation() {
-	//    if loc.LeadingComments == nil {
-	//        continue
-	//    }
-	//    var p []string
-	//    for _, n := range loc.Path {
-	//        p = append(p, strconv.Itoa(int(n)))
-	//    }
-	//    file.Comments[strings.Join(p, ",")] = loc
-	// }
-}
-
 // func UnmarshalFiles(bytes []byte) ([]*descriptorpb.FileProto, error) {
 //    fileDesc := &descriptorpb.FileDescriptorProto{}
 //    if err := proto.Unmarshal(bytes, fileDesc); err != nil {
@@ -308,3 +337,50 @@ func (f *File) ExtractComments() {
 //    }
 //    return fileDesc.File, nil
 // }
+
+func (f *File) HasOption(extension protoreflect.ExtensionType) bool {
+	return hasOption(f.proto().GetOptions(), extension)
+}
+
+func (f *File) GetOption(extension protoreflect.ExtensionType) interface{} {
+	return getOption(f.proto().GetOptions(), extension)
+}
+
+func (f *File) GetBoolOption(extension protoreflect.ExtensionType) bool {
+	return getBoolOption(f.proto().GetOptions(), extension)
+}
+
+func (f *File) GetInt64Option(extension protoreflect.ExtensionType) int64 {
+	return getInt64Option(f.proto().GetOptions(), extension)
+}
+
+func (f *File) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
+	return getFloat64Option(f.proto().GetOptions(), extension)
+}
+
+func (f *File) GetStringOption(extension protoreflect.ExtensionType) string {
+	return getStringOption(f.proto().GetOptions(), extension)
+}
+
+func (f *File) SetOption(extension protoreflect.ExtensionType, value interface{}) *File {
+	if f != nil && f.Proto != nil {
+		setOption(&f.Proto.Options, extension, value)
+	}
+	return f
+}
+
+func (f *File) SetBoolOption(extension protoreflect.ExtensionType, value bool) *File {
+	return f.SetOption(extension, value)
+}
+
+func (f *File) SetStringOption(extension protoreflect.ExtensionType, value string) *File {
+	return f.SetOption(extension, value)
+}
+
+func (f *File) SetInt64Option(extension protoreflect.ExtensionType, value int64) *File {
+	return f.SetOption(extension, value)
+}
+
+func (f *File) SetFloat64Option(extension protoreflect.ExtensionType, value float64) *File {
+	return f.SetOption(extension, value)
+}
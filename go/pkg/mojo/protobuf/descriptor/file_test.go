@@ -1 +1,98 @@
 package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestNewFileFrom_Comments(t *testing.T) {
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("foo.proto"),
+		Package: proto.String("foo"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Foo"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:   proto.String("bar"),
+				Number: proto.Int32(1),
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+			NestedType: []*descriptorpb.DescriptorProto{{Name: proto.String("Inner")}},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name:  proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("FooService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{Name: proto.String("Get")}},
+		}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{Path: []int32{4, 0}, LeadingComments: proto.String(" Foo message\n"), LeadingDetachedComments: []string{" detached\n"}},
+				{Path: []int32{4, 0, 2, 0}, TrailingComments: proto.String(" bar field\n")},
+				{Path: []int32{4, 0, 3, 0}, LeadingComments: proto.String(" Inner message\n")},
+				{Path: []int32{5, 0, 2, 0}, LeadingComments: proto.String(" unspecified\n")},
+				{Path: []int32{6, 0, 2, 0}, LeadingComments: proto.String(" Get method\n")},
+			},
+		},
+	}
+
+	file := NewFileFrom(fd)
+	foo := file.GetMessage("Foo")
+	assert.Equal(t, protoreflect.SourcePath{4, 0}, foo.Path)
+	assert.Equal(t, Comments(" Foo message\n"), foo.LeadingComments())
+	assert.Equal(t, []Comments{" detached\n"}, foo.Comments.LeadingDetached)
+	assert.Equal(t, Comments(" bar field\n"), foo.GetField("bar").TrailingComments())
+	assert.Equal(t, protoreflect.SourcePath{4, 0, 3, 0}, foo.GetMessage("Inner").Path)
+	assert.Equal(t, Comments(" Inner message\n"), foo.GetMessage("Inner").LeadingComments())
+	assert.Equal(t, Comments(" unspecified\n"), file.GetEnum("Kind").GetValue("KIND_UNSPECIFIED").LeadingComments())
+	assert.Equal(t, Comments(" Get method\n"), file.GetService("FooService").GetMethod("Get").LeadingComments())
+	assert.Equal(t, Comments(""), file.GetService("FooService").LeadingComments())
+}
+
+func TestFile_UpdateSourceCodeInfo(t *testing.T) {
+	file := NewFileWithName("foo.proto", "foo")
+	message := NewMessage(file).SetName("Foo")
+	message.SetLeadingComments(" Foo message\n")
+	message.AppendField(NewField(message, "bar").SetType("String").SetNumber(1))
+	message.Fields[0].SetTrailingComments(" bar field\n")
+	file.AppendMessage(message)
+
+	file.UpdateSourceCodeInfo()
+
+	wrapped := NewFileFrom(file.Proto)
+	foo := wrapped.GetMessage("Foo")
+	assert.Equal(t, Comments(" Foo message\n"), foo.LeadingComments())
+	assert.Equal(t, Comments(" bar field\n"), foo.GetField("bar").TrailingComments())
+
+	locations := file.Proto.GetSourceCodeInfo().GetLocation()
+	if assert.Equal(t, 3, len(locations)) {
+		assert.Equal(t, []int32{4, 0}, locations[1].Path)
+		assert.Equal(t, []int32{5, 0, 7, 1}, locations[1].Span)
+		assert.Equal(t, []int32{4, 0, 2, 0}, locations[2].Path)
+		assert.Equal(t, []int32{6, 4, 19}, locations[2].Span)
+	}
+}
+
+func TestFile_UpdateSourceCodeInfo_Extensions(t *testing.T) {
+	file := NewFileWithName("foo.proto", "foo")
+	file.AppendDependency("google/protobuf/descriptor.proto")
+	file.AppendExtension(NewExtension(file, ".google.protobuf.MessageOptions", "table").SetType("String").SetNumber(50001))
+	file.Extensions[0].SetLeadingComments(" the table name\n")
+
+	file.UpdateSourceCodeInfo()
+	assert.Contains(t, file.PrintToString(), "extend .google.protobuf.MessageOptions {\n    // the table name\n    string table = 50001;\n}\n")
+
+	wrapped := NewFileFrom(file.Proto)
+	assert.Equal(t, Comments(" the table name\n"), wrapped.Extensions[0].LeadingComments())
+
+	locations := file.Proto.GetSourceCodeInfo().GetLocation()
+	if assert.Equal(t, 2, len(locations)) {
+		assert.Equal(t, []int32{7, 0}, locations[1].Path)
+		assert.Equal(t, []int32{8, 4, 25}, locations[1].Span)
+	}
+}
@@ -0,0 +1,223 @@
+package descriptor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// UnresolvedTypeError reports a type reference which could not be resolved in the Packages
+type UnresolvedTypeError struct {
+	File     string // name of the file in which the reference is declared
+	Element  string // full name of the field or method referencing the type
+	TypeName string // the type name could not be resolved
+}
+
+func (e *UnresolvedTypeError) Error() string {
+	return fmt.Sprintf("%s: the type %q referenced by %q could not be resolved", e.File, e.TypeName, e.Element)
+}
+
+// LinkErrors collects all the unresolved references found when linking the Packages
+type LinkErrors []*UnresolvedTypeError
+
+func (e LinkErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e LinkErrors) Unwrap() []error {
+	var errs []error
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// IsUnresolvedTypeError checks whether the err is or contains an UnresolvedTypeError
+func IsUnresolvedTypeError(err error) bool {
+	var unresolved *UnresolvedTypeError
+	return errors.As(err, &unresolved)
+}
+
+// Link resolves the type names of all the fields, extensions and methods in the Packages into the
+// Field.Enum, Field.Message, Field.Extendee, Method.Input and Method.Output, following the protobuf
+// scoping rules relative to the containing message and package. All the unresolved references are
+// returned as LinkErrors.
+func (p *Packages) Link() error {
+	if p == nil {
+		return nil
+	}
+
+	var errs LinkErrors
+	for _, file := range p.sortedFiles() {
+		for _, message := range file.Messages {
+			errs = append(errs, p.linkMessage(message)...)
+		}
+		for _, service := range file.Services {
+			for _, method := range service.Methods {
+				errs = append(errs, p.linkMethod(method)...)
+			}
+		}
+		for _, extension := range file.Extensions {
+			errs = append(errs, p.linkExtension(extension)...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *Packages) sortedFiles() []*File {
+	var names []string
+	for name := range p.FilesByPath {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []*File
+	for _, name := range names {
+		files = append(files, p.FilesByPath[name])
+	}
+	return files
+}
+
+func (p *Packages) linkMessage(message *Message) LinkErrors {
+	var errs LinkErrors
+	for _, field := range message.Fields {
+		if err := p.linkField(field); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, msg := range message.Messages {
+		errs = append(errs, p.linkMessage(msg)...)
+	}
+	for _, extension := range message.Extensions {
+		errs = append(errs, p.linkExtension(extension)...)
+	}
+	return errs
+}
+
+func (p *Packages) linkExtension(extension *Field) LinkErrors {
+	var errs LinkErrors
+	if err := p.linkField(extension); err != nil {
+		errs = append(errs, err)
+	}
+
+	if message, _ := p.Resolve(extension.scope(), extension.GetExtendee()); message != nil {
+		extension.Extendee = message
+		p.indexExtension(message.GetFullName(), extension)
+	} else {
+		errs = append(errs, &UnresolvedTypeError{
+			File:     extension.File.GetName(),
+			Element:  extension.GetFullName(),
+			TypeName: extension.GetExtendee(),
+		})
+	}
+	return errs
+}
+
+func (p *Packages) linkField(field *Field) *UnresolvedTypeError {
+	switch field.Proto.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+	default:
+		if field.Proto.Type != nil || len(field.Proto.GetTypeName()) == 0 {
+			return nil
+		}
+	}
+
+	if message, enum := p.Resolve(field.scope(), field.Proto.GetTypeName()); message != nil {
+		field.Message = message
+		if field.Proto.Type == nil {
+			field.Proto.Type = &messageType
+		}
+	} else if enum != nil {
+		field.Enum = enum
+		if field.Proto.Type == nil {
+			field.Proto.Type = &enumType
+		}
+	} else {
+		return &UnresolvedTypeError{
+			File:     field.File.GetName(),
+			Element:  field.GetFullName(),
+			TypeName: field.Proto.GetTypeName(),
+		}
+	}
+	return nil
+}
+
+func (p *Packages) linkMethod(method *Method) LinkErrors {
+	var errs LinkErrors
+	scope := method.Parent.GetFullName()
+	resolve := func(typeName string) *Message {
+		message, _ := p.Resolve(scope, typeName)
+		if message == nil {
+			errs = append(errs, &UnresolvedTypeError{
+				File:     method.File.GetName(),
+				Element:  concatFullName(scope, method.GetName()),
+				TypeName: typeName,
+			})
+		}
+		return message
+	}
+
+	method.Input = resolve(method.Proto.GetInputType())
+	method.Output = resolve(method.Proto.GetOutputType())
+	return errs
+}
+
+// Resolve looks up the type name in the scope following the protobuf scoping rules,
+// which searches the innermost scope first and goes outward until the first component
+// of the name is found.
+//
+// The type name starting with a dot is treated as the fully-qualified name.
+func (p *Packages) Resolve(scope string, typeName string) (*Message, *Enum) {
+	if p == nil || len(typeName) == 0 {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(typeName, ".") {
+		return p.GetMessage(typeName), p.GetEnum(typeName)
+	}
+
+	first := typeName
+	if i := strings.Index(typeName, "."); i >= 0 {
+		first = typeName[:i]
+	}
+
+	for {
+		candidate := concatFullName(scope, first)
+		if p.isSymbolExist(candidate) {
+			fullName := concatFullName(scope, typeName)
+			return p.GetMessage(fullName), p.GetEnum(fullName)
+		}
+		if len(scope) == 0 {
+			break
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+	return nil, nil
+}
+
+func (p *Packages) isSymbolExist(name string) bool {
+	if p.GetMessage(name) != nil || p.GetEnum(name) != nil {
+		return true
+	}
+	for pkg := range p.Files {
+		if pkg == name || strings.HasPrefix(pkg, name+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package descriptor
+
+import (
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// LoadFileDescriptorSet wraps all the files in the set and links the types and methods.
+//
+// The files will be added even if some references could not be resolved, in which case
+// the LinkErrors will be returned.
+func (p *Packages) LoadFileDescriptorSet(set *descriptorpb.FileDescriptorSet) error {
+	if p == nil || set == nil {
+		return nil
+	}
+
+	for _, fd := range set.File {
+		file := NewFileFrom(fd)
+		file.Packages = p
+		p.AddFile(file)
+	}
+	return p.Link()
+}
+
+// LoadBytes loads the binary encoded FileDescriptorSet, such as the output of `protoc -o`
+func (p *Packages) LoadBytes(data []byte) error {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return err
+	}
+	return p.LoadFileDescriptorSet(set)
+}
+
+func (p *Packages) LoadReader(reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return p.LoadBytes(data)
+}
+
+func (p *Packages) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return p.LoadBytes(data)
+}
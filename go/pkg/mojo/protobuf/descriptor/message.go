@@ -3,6 +3,8 @@ package descriptor
 import (
     "strings"
 
+    "google.golang.org/protobuf/proto"
+    "google.golang.org/protobuf/reflect/protoreflect"
     "google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -18,8 +20,9 @@ type Message struct {
     Enums    []*Enum    // Inner Enums, if any.
     Messages []*Message // Inner Messages, if any.
 
-    Fields []*Field // message field declarations
-    Oneofs []*Oneof // message oneof declarations
+    Fields     []*Field // message field declarations
+    Oneofs     []*Oneof // message oneof declarations
+    Extensions []*Field // extension declarations nested in the message
 }
 
 func NewMessage(file *File) *Message {
@@ -38,26 +41,27 @@ func NewMessageFrom(file *File, proto *descriptorpb.DescriptorProto) *Message {
         },
         Proto: proto,
     }
-    //
-    //var loc Location
-    //if parent != nil {
-    //    loc = parent.Location.AppendPath(DescriptorProto_NestedType_field_number, desc.Index())
-    //} else {
-    //    loc = f.location.AppendPath(FileDescriptorProto_MessageType_field_number, desc.Index())
-    //}
+    message.FullName = concatFullName(file.GetPackageName(), proto.GetName())
 
-    for _, enum := range proto.EnumType {
+    // the Append methods append to the proto too, so detach the elements first
+    enums, messages, fields, oneofs, extensions := proto.EnumType, proto.NestedType, proto.Field, proto.OneofDecl, proto.Extension
+    proto.EnumType, proto.NestedType, proto.Field, proto.OneofDecl, proto.Extension = nil, nil, nil, nil, nil
+
+    for _, enum := range enums {
         message.AppendInnerEnum(NewEnumFrom(file, enum))
     }
-    for _, msg := range proto.NestedType {
+    for _, msg := range messages {
         message.AppendMessage(NewMessageFrom(file, msg))
     }
-    for _, field := range proto.Field {
+    for _, field := range fields {
         message.AppendField(NewFieldFrom(message, field))
     }
-    for _, oneof := range proto.OneofDecl {
+    for _, oneof := range oneofs {
         message.AppendOneof(NewOneofFrom(message, oneof))
     }
+    for _, extension := range extensions {
+        message.AppendExtension(NewExtensionFrom(file, message, extension))
+    }
 
     // Resolve local references between fields and oneofs.
     for _, field := range message.Fields {
@@ -123,6 +127,17 @@ func (m *Message) GetField(name string) *Field {
     return nil
 }
 
+func (m *Message) GetFieldByNumber(number int32) *Field {
+    if m != nil {
+        for _, f := range m.Fields {
+            if f.GetNumber() == number {
+                return f
+            }
+        }
+    }
+    return nil
+}
+
 func (m *Message) IsFieldExist(name string) bool {
     return m.GetField(name) != nil
 }
@@ -150,11 +165,31 @@ func (m *Message) GetName() string {
 
 func (m *Message) GetFullName() string {
     if m != nil {
+        if len(m.FullName) == 0 {
+            m.updateFullName()
+        }
         return m.FullName
     }
     return ""
 }
 
+// updateFullName updates the full name of the message and all its nested types,
+// the full name of the nested type is prefixed with the full name of its parent.
+func (m *Message) updateFullName() {
+    if m.Parent != nil {
+        m.FullName = concatFullName(m.Parent.GetFullName(), m.GetName())
+    } else {
+        m.FullName = concatFullName(m.GetPackageName(), m.GetName())
+    }
+
+    for _, msg := range m.Messages {
+        msg.updateFullName()
+    }
+    for _, enum := range m.Enums {
+        enum.updateFullName()
+    }
+}
+
 func (m *Message) GetPackageName() string {
     if m != nil && m.File != nil {
         return m.File.GetPackageName()
@@ -162,6 +197,13 @@ func (m *Message) GetPackageName() string {
     return ""
 }
 
+func (m *Message) GetFields() []*Field {
+    if m != nil {
+        return m.Fields
+    }
+    return nil
+}
+
 func (m *Message) GetOneofs() []*Oneof {
     if m != nil {
         return m.Oneofs
@@ -169,9 +211,14 @@ func (m *Message) GetOneofs() []*Oneof {
     return nil
 }
 
+// GetLastOneof returns the last oneof declared in the message, the synthetic oneofs are skipped
 func (m *Message) GetLastOneof() *Oneof {
-    if m != nil && len(m.Oneofs) > 0 {
-        return m.Oneofs[len(m.Oneofs)-1]
+    if m != nil {
+        for i := len(m.Oneofs) - 1; i >= 0; i-- {
+            if !m.Oneofs[i].IsSynthetic() {
+                return m.Oneofs[i]
+            }
+        }
     }
     return nil
 }
@@ -179,6 +226,7 @@ func (m *Message) GetLastOneof() *Oneof {
 func (m *Message) AppendMessage(msg *Message) *Message {
     if m != nil && m.Proto != nil {
         msg.Parent = m
+        msg.updateFullName()
         m.Messages = append(m.Messages, msg)
         m.Proto.NestedType = append(m.Proto.NestedType, msg.Proto)
     }
@@ -188,28 +236,73 @@ func (m *Message) AppendMessage(msg *Message) *Message {
 func (m *Message) AppendInnerEnum(enum *Enum) *Message {
     if m != nil && m.Proto != nil {
         enum.Parent = m
+        enum.updateFullName()
         m.Enums = append(m.Enums, enum)
         m.Proto.EnumType = append(m.Proto.EnumType, enum.Proto)
     }
     return m
 }
 
+// AppendField appends the field, and the map entry message of the map field created by NewMapField
 func (m *Message) AppendField(field *Field) *Message {
     if m != nil && m.Proto != nil {
+        if entry := field.mapEntry(); entry != nil && entry.Parent == m && m.GetMessage(entry.GetName()) == nil {
+            m.AppendMessage(entry)
+        }
         m.Fields = append(m.Fields, field)
         m.Proto.Field = append(m.Proto.Field, field.Proto)
     }
     return m
 }
 
+// AppendOneof appends the oneof before the synthetic oneofs, which should be after all the real oneofs
 func (m *Message) AppendOneof(oneof *Oneof) *Message {
     if m != nil && m.Proto != nil {
-        m.Oneofs = append(m.Oneofs, oneof)
-        m.Proto.OneofDecl = append(m.Proto.OneofDecl, oneof.Proto)
+        index := len(m.Oneofs)
+        if !oneof.IsSynthetic() {
+            for index > 0 && m.Oneofs[index-1].IsSynthetic() {
+                index--
+            }
+        }
+
+        if index == len(m.Oneofs) {
+            m.Oneofs = append(m.Oneofs, oneof)
+            m.Proto.OneofDecl = append(m.Proto.OneofDecl, oneof.Proto)
+            return m
+        }
+
+        m.Oneofs = append(m.Oneofs[:index:index], append([]*Oneof{oneof}, m.Oneofs[index:]...)...)
+        m.Proto.OneofDecl = append(m.Proto.OneofDecl[:index:index], append([]*descriptorpb.OneofDescriptorProto{oneof.Proto}, m.Proto.OneofDecl[index:]...)...)
+        for _, field := range m.Fields {
+            if i := field.Proto.OneofIndex; i != nil && *i >= int32(index) {
+                field.Proto.OneofIndex = proto.Int32(*i + 1)
+            }
+        }
     }
     return m
 }
 
+// AppendExtension appends the extension declared in the scope of the message, the extendee of which should be set
+func (m *Message) AppendExtension(extension *Field) *Message {
+    if m != nil && m.Proto != nil {
+        extension.Parent = m
+        m.Extensions = append(m.Extensions, extension)
+        m.Proto.Extension = append(m.Proto.Extension, extension.Proto)
+    }
+    return m
+}
+
+func (m *Message) GetExtension(name string) *Field {
+    if m != nil {
+        for _, e := range m.Extensions {
+            if e.GetName() == name {
+                return e
+            }
+        }
+    }
+    return nil
+}
+
 func (m *Message) AppendOneofWith(name string) *Message {
     if m != nil && m.Proto != nil {
         m.AppendOneof(NewOneof(m, name))
@@ -230,7 +323,7 @@ func concatFullName(pkg string, name string) string {
 func (m *Message) SetName(name string) *Message {
     if m != nil && m.Proto != nil {
         m.Proto.Name = &name
-        m.FullName = concatFullName(m.File.GetPackageName(), name)
+        m.updateFullName()
     }
     return m
 }
@@ -262,3 +355,50 @@ func (m *Message) SetMapEntry(value bool) *Message {
     }
     return m
 }
+
+func (m *Message) HasOption(extension protoreflect.ExtensionType) bool {
+    return hasOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Message) GetOption(extension protoreflect.ExtensionType) interface{} {
+    return getOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Message) GetBoolOption(extension protoreflect.ExtensionType) bool {
+    return getBoolOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Message) GetInt64Option(extension protoreflect.ExtensionType) int64 {
+    return getInt64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *Message) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
+    return getFloat64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *Message) GetStringOption(extension protoreflect.ExtensionType) string {
+    return getStringOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Message) SetOption(extension protoreflect.ExtensionType, value interface{}) *Message {
+    if m != nil && m.Proto != nil {
+        setOption(&m.Proto.Options, extension, value)
+    }
+    return m
+}
+
+func (m *Message) SetBoolOption(extension protoreflect.ExtensionType, value bool) *Message {
+    return m.SetOption(extension, value)
+}
+
+func (m *Message) SetStringOption(extension protoreflect.ExtensionType, value string) *Message {
+    return m.SetOption(extension, value)
+}
+
+func (m *Message) SetInt64Option(extension protoreflect.ExtensionType, value int64) *Message {
+    return m.SetOption(extension, value)
+}
+
+func (m *Message) SetFloat64Option(extension protoreflect.ExtensionType, value float64) *Message {
+    return m.SetOption(extension, value)
+}
@@ -3,6 +3,7 @@ package descriptor
 import (
     "bytes"
     "github.com/mholt/archiver/v3"
+    "github.com/mojo-lang/core/go/pkg/mojo"
     "github.com/stretchr/testify/assert"
     "google.golang.org/protobuf/proto"
     "google.golang.org/protobuf/types/descriptorpb"
@@ -62,3 +63,16 @@ func TestNewMessageFrom2(t *testing.T) {
     assert.Equal(t, 1, len(msg.Fields))
     assert.Equal(t, "fields", msg.Fields[0].GetName())
 }
+
+func TestMessage_SetOption(t *testing.T) {
+    message := NewMessage(NewFile()).SetName("Foo")
+    assert.False(t, message.HasOption(mojo.E_Getters))
+    assert.Nil(t, message.GetOption(mojo.E_Getters))
+
+    message.SetBoolOption(mojo.E_Getters, true)
+    assert.True(t, message.HasOption(mojo.E_Getters))
+    assert.True(t, message.GetBoolOption(mojo.E_Getters))
+
+    value := NewEnumValue(NewEnum(NewFile()), "FOO_ONE", 1).SetStringOption(mojo.E_EnumvalueAlias, "one")
+    assert.Equal(t, "one", value.GetStringOption(mojo.E_EnumvalueAlias))
+}
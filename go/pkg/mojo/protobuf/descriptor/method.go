@@ -1,6 +1,33 @@
 package descriptor
 
-import "google.golang.org/protobuf/types/descriptorpb"
+import (
+    "google.golang.org/protobuf/proto"
+    "google.golang.org/protobuf/reflect/protoreflect"
+    "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// MethodKind classifies the method by the streaming of its input and output
+type MethodKind int
+
+const (
+    UnaryMethod MethodKind = iota
+    ClientStreamingMethod
+    ServerStreamingMethod
+    BidiStreamingMethod
+)
+
+func (k MethodKind) String() string {
+    switch k {
+    case ClientStreamingMethod:
+        return "client_streaming"
+    case ServerStreamingMethod:
+        return "server_streaming"
+    case BidiStreamingMethod:
+        return "bidi_streaming"
+    default:
+        return "unary"
+    }
+}
 
 // A Method describes a method in a service.
 type Method struct {
@@ -107,3 +134,100 @@ func (m *Method) SetOutput(output *Message) *Method {
     }
     return m
 }
+
+func (m *Method) IsClientStreaming() bool {
+    return m.proto().GetClientStreaming()
+}
+
+func (m *Method) SetClientStreaming(streaming bool) *Method {
+    if m != nil && m.Proto != nil {
+        if streaming {
+            m.Proto.ClientStreaming = proto.Bool(true)
+        } else {
+            m.Proto.ClientStreaming = nil
+        }
+    }
+    return m
+}
+
+func (m *Method) IsServerStreaming() bool {
+    return m.proto().GetServerStreaming()
+}
+
+func (m *Method) SetServerStreaming(streaming bool) *Method {
+    if m != nil && m.Proto != nil {
+        if streaming {
+            m.Proto.ServerStreaming = proto.Bool(true)
+        } else {
+            m.Proto.ServerStreaming = nil
+        }
+    }
+    return m
+}
+
+// GetKind returns the kind of the method by its client and server streaming
+func (m *Method) GetKind() MethodKind {
+    switch {
+    case m.IsClientStreaming() && m.IsServerStreaming():
+        return BidiStreamingMethod
+    case m.IsClientStreaming():
+        return ClientStreamingMethod
+    case m.IsServerStreaming():
+        return ServerStreamingMethod
+    default:
+        return UnaryMethod
+    }
+}
+
+// SetKind sets the client and server streaming of the method by the kind
+func (m *Method) SetKind(kind MethodKind) *Method {
+    return m.SetClientStreaming(kind == ClientStreamingMethod || kind == BidiStreamingMethod).
+        SetServerStreaming(kind == ServerStreamingMethod || kind == BidiStreamingMethod)
+}
+
+func (m *Method) HasOption(extension protoreflect.ExtensionType) bool {
+    return hasOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Method) GetOption(extension protoreflect.ExtensionType) interface{} {
+    return getOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Method) GetBoolOption(extension protoreflect.ExtensionType) bool {
+    return getBoolOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Method) GetInt64Option(extension protoreflect.ExtensionType) int64 {
+    return getInt64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *Method) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
+    return getFloat64Option(m.proto().GetOptions(), extension)
+}
+
+func (m *Method) GetStringOption(extension protoreflect.ExtensionType) string {
+    return getStringOption(m.proto().GetOptions(), extension)
+}
+
+func (m *Method) SetOption(extension protoreflect.ExtensionType, value interface{}) *Method {
+    if m != nil && m.Proto != nil {
+        setOption(&m.Proto.Options, extension, value)
+    }
+    return m
+}
+
+func (m *Method) SetBoolOption(extension protoreflect.ExtensionType, value bool) *Method {
+    return m.SetOption(extension, value)
+}
+
+func (m *Method) SetStringOption(extension protoreflect.ExtensionType, value string) *Method {
+    return m.SetOption(extension, value)
+}
+
+func (m *Method) SetInt64Option(extension protoreflect.ExtensionType, value int64) *Method {
+    return m.SetOption(extension, value)
+}
+
+func (m *Method) SetFloat64Option(extension protoreflect.ExtensionType, value float64) *Method {
+    return m.SetOption(extension, value)
+}
@@ -0,0 +1,128 @@
+package descriptor
+
+import (
+	"strings"
+
+	"github.com/mojo-lang/core/go/pkg/mojo/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// MojoTypeMapping maps the protobuf types to the mojo type names, the reverse of the mapping used by SetType
+type MojoTypeMapping struct {
+	// Scalars maps the scalar field types to the mojo types
+	Scalars map[descriptorpb.FieldDescriptorProto_Type]string
+
+	// Types maps the full names of the messages and enums to the mojo types, like the well-known types.
+	// The messages and enums not in the map are mapped to their full names.
+	Types map[string]string
+}
+
+// NewMojoTypeMapping returns a copy of the default mapping, which maps the scalar types to the mojo core types,
+// and the well-known types and the wrappers to their counterparts in mojo core.
+func NewMojoTypeMapping() *MojoTypeMapping {
+	mapping := &MojoTypeMapping{
+		Scalars: make(map[descriptorpb.FieldDescriptorProto_Type]string),
+		Types:   make(map[string]string),
+	}
+	for t, name := range defaultMojoTypeMapping.Scalars {
+		mapping.Scalars[t] = name
+	}
+	for t, name := range defaultMojoTypeMapping.Types {
+		mapping.Types[t] = name
+	}
+	return mapping
+}
+
+func (m *MojoTypeMapping) SetScalar(t descriptorpb.FieldDescriptorProto_Type, mojoType string) *MojoTypeMapping {
+	m.Scalars[t] = mojoType
+	return m
+}
+
+// SetType sets the mojo type for the message or enum, the full name may start with a leading dot
+func (m *MojoTypeMapping) SetType(fullName string, mojoType string) *MojoTypeMapping {
+	m.Types[strings.TrimPrefix(fullName, ".")] = mojoType
+	return m
+}
+
+var defaultMojoTypeMapping = &MojoTypeMapping{
+	Scalars: map[descriptorpb.FieldDescriptorProto_Type]string{
+		descriptorpb.FieldDescriptorProto_TYPE_BOOL:     core.BoolTypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_INT32:    core.Int32TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT32:   core.Int32TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32: core.Int32TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT32:   core.UInt32TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED32:  core.UInt32TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_INT64:    core.Int64TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64:   core.Int64TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64: core.Int64TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT64:   core.UInt64TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED64:  core.UInt64TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_FLOAT:    core.Float32TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:   core.Float64TypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_STRING:   core.StringTypeName,
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES:    core.BytesTypeName,
+	},
+	Types: map[string]string{
+		"google.protobuf.Any":       core.AnyTypeName,
+		"google.protobuf.Timestamp": core.TimestampTypeName,
+		"google.protobuf.Duration":  core.DurationTypeName,
+		"google.protobuf.FieldMask": core.FieldMaskTypeName,
+		"google.protobuf.Struct":    core.ObjectTypeName,
+		"google.protobuf.Value":     core.ValueTypeName,
+		"google.protobuf.ListValue": core.ValuesTypeName,
+		"google.protobuf.NullValue": core.NullTypeName,
+		"google.protobuf.Empty":     core.NullTypeName,
+
+		"google.protobuf.BoolValue":   core.BoolValueTypeName,
+		"google.protobuf.Int32Value":  core.Int32ValueTypeName,
+		"google.protobuf.UInt32Value": core.UInt32ValueTypeName,
+		"google.protobuf.Int64Value":  core.Int64ValueTypeName,
+		"google.protobuf.UInt64Value": core.UInt64ValueTypeName,
+		"google.protobuf.FloatValue":  core.Float32ValueTypeName,
+		"google.protobuf.DoubleValue": core.Float64ValueTypeName,
+		"google.protobuf.StringValue": core.StringValueTypeName,
+		"google.protobuf.BytesValue":  core.BytesValueTypeName,
+	},
+}
+
+// GetMojoTypeName returns the mojo type name of the field by the mapping, the default mapping is used if nil.
+// The repeated fields are mapped to "Array<T>", and the map fields to "Map<K, V>".
+func (m *Field) GetMojoTypeName(mapping *MojoTypeMapping) string {
+	if m == nil || m.Proto == nil {
+		return ""
+	}
+	if mapping == nil {
+		mapping = defaultMojoTypeMapping
+	}
+
+	if m.IsMap() {
+		return core.MapTypeName + "<" + m.MapKey().elementMojoTypeName(mapping) + ", " + m.MapValue().elementMojoTypeName(mapping) + ">"
+	}
+	if m.IsRepeated() {
+		return core.ArrayTypeName + "<" + m.elementMojoTypeName(mapping) + ">"
+	}
+	return m.elementMojoTypeName(mapping)
+}
+
+// elementMojoTypeName returns the mojo type name of the field ignoring the label
+func (m *Field) elementMojoTypeName(mapping *MojoTypeMapping) string {
+	fd := m.proto()
+	switch fd.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_ENUM, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+	default:
+		if fd.Type != nil {
+			return mapping.Scalars[fd.GetType()]
+		}
+	}
+
+	fullName := strings.TrimPrefix(fd.GetTypeName(), ".")
+	if m.Message != nil {
+		fullName = m.Message.GetFullName()
+	} else if m.Enum != nil {
+		fullName = m.Enum.GetFullName()
+	}
+	if name, ok := mapping.Types[fullName]; ok {
+		return name
+	}
+	return fullName
+}
@@ -1,6 +1,9 @@
 package descriptor
 
-import "google.golang.org/protobuf/types/descriptorpb"
+import (
+    "google.golang.org/protobuf/reflect/protoreflect"
+    "google.golang.org/protobuf/types/descriptorpb"
+)
 
 // An Oneof describes a message oneof.
 type Oneof struct {
@@ -52,3 +55,50 @@ func (o *Oneof) AppendField(field *Field) *Oneof {
     }
     return o
 }
+
+func (o *Oneof) HasOption(extension protoreflect.ExtensionType) bool {
+    return hasOption(o.proto().GetOptions(), extension)
+}
+
+func (o *Oneof) GetOption(extension protoreflect.ExtensionType) interface{} {
+    return getOption(o.proto().GetOptions(), extension)
+}
+
+func (o *Oneof) GetBoolOption(extension protoreflect.ExtensionType) bool {
+    return getBoolOption(o.proto().GetOptions(), extension)
+}
+
+func (o *Oneof) GetInt64Option(extension protoreflect.ExtensionType) int64 {
+    return getInt64Option(o.proto().GetOptions(), extension)
+}
+
+func (o *Oneof) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
+    return getFloat64Option(o.proto().GetOptions(), extension)
+}
+
+func (o *Oneof) GetStringOption(extension protoreflect.ExtensionType) string {
+    return getStringOption(o.proto().GetOptions(), extension)
+}
+
+func (o *Oneof) SetOption(extension protoreflect.ExtensionType, value interface{}) *Oneof {
+    if o != nil && o.Proto != nil {
+        setOption(&o.Proto.Options, extension, value)
+    }
+    return o
+}
+
+func (o *Oneof) SetBoolOption(extension protoreflect.ExtensionType, value bool) *Oneof {
+    return o.SetOption(extension, value)
+}
+
+func (o *Oneof) SetStringOption(extension protoreflect.ExtensionType, value string) *Oneof {
+    return o.SetOption(extension, value)
+}
+
+func (o *Oneof) SetInt64Option(extension protoreflect.ExtensionType, value int64) *Oneof {
+    return o.SetOption(extension, value)
+}
+
+func (o *Oneof) SetFloat64Option(extension protoreflect.ExtensionType, value float64) *Oneof {
+    return o.SetOption(extension, value)
+}
@@ -0,0 +1,101 @@
+package descriptor
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// the shared implementation of the extension options on the *descriptorpb.XxxOptions messages,
+// the options may be a typed nil pointer.
+
+func hasOption(options proto.Message, extension protoreflect.ExtensionType) bool {
+	return options != nil && options.ProtoReflect().IsValid() && proto.HasExtension(options, extension)
+}
+
+func getOption(options proto.Message, extension protoreflect.ExtensionType) interface{} {
+	if options != nil && options.ProtoReflect().IsValid() {
+		return proto.GetExtension(options, extension)
+	}
+	return nil
+}
+
+// setOption sets the extension option, and allocates the options message if nil
+func setOption[T any, O interface {
+	*T
+	proto.Message
+}](options *O, extension protoreflect.ExtensionType, value interface{}) {
+	proto.SetExtension(optionsMessage(options, true), extension, value)
+}
+
+// optionsMessage returns the options message, allocates it if nil and create is true
+func optionsMessage[T any, O interface {
+	*T
+	proto.Message
+}](options *O, create bool) proto.Message {
+	if *options == nil {
+		if !create {
+			return nil
+		}
+		*options = new(T)
+	}
+	return *options
+}
+
+// holderOptions returns the options message of the descriptor wrapper, nil if not set and create is false
+func holderOptions(holder OptionHolder, create bool) proto.Message {
+	switch h := holder.(type) {
+	case *File:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	case *Message:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	case *Field:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	case *Oneof:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	case *Enum:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	case *EnumValue:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	case *Service:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	case *Method:
+		if h != nil && h.Proto != nil {
+			return optionsMessage(&h.Proto.Options, create)
+		}
+	}
+	return nil
+}
+
+func getBoolOption(options proto.Message, extension protoreflect.ExtensionType) bool {
+	v, _ := getOption(options, extension).(bool)
+	return v
+}
+
+func getInt64Option(options proto.Message, extension protoreflect.ExtensionType) int64 {
+	v, _ := getOption(options, extension).(int64)
+	return v
+}
+
+func getFloat64Option(options proto.Message, extension protoreflect.ExtensionType) float64 {
+	v, _ := getOption(options, extension).(float64)
+	return v
+}
+
+func getStringOption(options proto.Message, extension protoreflect.ExtensionType) string {
+	v, _ := getOption(options, extension).(string)
+	return v
+}
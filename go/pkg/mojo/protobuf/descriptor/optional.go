@@ -0,0 +1,118 @@
+package descriptor
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// SyntheticOneofName returns the name of the synthetic oneof for the proto3 optional field as protoc does,
+// which is the field name prefixed with "_", and then with "X" until it's not conflicted in the message.
+func SyntheticOneofName(md *descriptorpb.DescriptorProto, fieldName string) string {
+	names := make(map[string]bool)
+	for _, field := range md.GetField() {
+		names[field.GetName()] = true
+	}
+	for _, oneof := range md.GetOneofDecl() {
+		names[oneof.GetName()] = true
+	}
+	for _, nested := range md.GetNestedType() {
+		names[nested.GetName()] = true
+	}
+	for _, enum := range md.GetEnumType() {
+		names[enum.GetName()] = true
+	}
+
+	name := fieldName
+	if !strings.HasPrefix(name, "_") {
+		name = "_" + name
+	}
+	for names[name] {
+		name = "X" + name
+	}
+	return name
+}
+
+// IsSynthetic checks whether the oneof is generated by protoc for the proto3 optional field
+func (o *Oneof) IsSynthetic() bool {
+	return o != nil && len(o.Fields) == 1 && o.Fields[0].proto().GetProto3Optional()
+}
+
+// GetRealOneofs returns the oneofs declared in the message, excluding the synthetic ones
+func (m *Message) GetRealOneofs() []*Oneof {
+	var oneofs []*Oneof
+	for _, oneof := range m.GetOneofs() {
+		if !oneof.IsSynthetic() {
+			oneofs = append(oneofs, oneof)
+		}
+	}
+	return oneofs
+}
+
+// GetSyntheticOneofs returns the synthetic oneofs of the proto3 optional fields
+func (m *Message) GetSyntheticOneofs() []*Oneof {
+	var oneofs []*Oneof
+	for _, oneof := range m.GetOneofs() {
+		if oneof.IsSynthetic() {
+			oneofs = append(oneofs, oneof)
+		}
+	}
+	return oneofs
+}
+
+// IsOptional checks whether the field is declared with the optional label, which is either the proto3
+// optional field or the proto2 optional field not in a oneof.
+func (m *Field) IsOptional() bool {
+	fd := m.proto()
+	if fd.GetProto3Optional() {
+		return true
+	}
+	return m != nil && m.File.GetSyntax() == Proto2Syntax &&
+		fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL && fd.OneofIndex == nil && m.Oneof == nil
+}
+
+// SetOptional sets or clears the optional label of the field.
+//
+// In the proto3 file, the synthetic oneof of the field is appended to or removed from the message too,
+// the repeated fields and the fields in the real oneofs are unchanged. In the other files, only the
+// label of the singular field is set to optional.
+func (m *Field) SetOptional(optional bool) *Field {
+	if m == nil || m.Proto == nil || m.IsRepeated() {
+		return m
+	}
+	if m.File.GetSyntax() != Proto3Syntax {
+		if optional {
+			m.Proto.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		}
+		return m
+	}
+	if m.Oneof != nil && !m.Oneof.IsSynthetic() {
+		return m
+	}
+
+	if optional {
+		if m.Proto.GetProto3Optional() {
+			return m
+		}
+		m.Proto.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		m.Proto.Proto3Optional = proto.Bool(true)
+
+		// the proto3 optional extensions have no synthetic oneofs
+		if parent := m.Parent; parent != nil && parent.Proto != nil && !m.IsExtension() {
+			oneof := NewOneof(parent, SyntheticOneofName(parent.Proto, m.GetName()))
+			parent.Oneofs = append(parent.Oneofs, oneof)
+			parent.Proto.OneofDecl = append(parent.Proto.OneofDecl, oneof.Proto)
+			oneof.AppendField(m)
+			m.Proto.OneofIndex = proto.Int32(int32(len(parent.Oneofs) - 1))
+		}
+	} else if m.Proto.GetProto3Optional() {
+		if m.Oneof.IsSynthetic() {
+			m.Parent.removeOneof(m.Oneof)
+			m.Oneof = nil
+			m.Proto.OneofIndex = nil
+		}
+		m.Proto.Proto3Optional = nil
+	}
+	return m
+}
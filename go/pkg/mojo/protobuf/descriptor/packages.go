@@ -1,6 +1,9 @@
 package descriptor
 
-import "strings"
+import (
+    "sort"
+    "strings"
+)
 
 type Packages struct {
     Files map[string][]*File
@@ -9,6 +12,9 @@ type Packages struct {
     EnumsByName    map[string]*Enum
     MessagesByName map[string]*Message
     ServicesByName map[string]*Service
+
+    ExtensionsByName     map[string]*Field
+    ExtensionsByExtendee map[string]map[int32]*Field // extensions by the full name of the extendee and the number
 }
 
 func NewPackages() *Packages {
@@ -18,39 +24,147 @@ func NewPackages() *Packages {
         EnumsByName:    make(map[string]*Enum),
         MessagesByName: make(map[string]*Message),
         ServicesByName: make(map[string]*Service),
+
+        ExtensionsByName:     make(map[string]*Field),
+        ExtensionsByExtendee: make(map[string]map[int32]*Field),
     }
 }
 
+// GetMessage get the message by the full name, the name may start with a leading dot
 func (p *Packages) GetMessage(name string) *Message {
     if p != nil {
-        if msg, ok := p.MessagesByName[name]; ok {
+        if msg, ok := p.MessagesByName[strings.TrimPrefix(name, ".")]; ok {
             return msg
         }
     }
     return nil
 }
 
+func (p *Packages) GetEnum(name string) *Enum {
+    if p != nil {
+        if enum, ok := p.EnumsByName[strings.TrimPrefix(name, ".")]; ok {
+            return enum
+        }
+    }
+    return nil
+}
+
+func (p *Packages) GetService(name string) *Service {
+    if p != nil {
+        if service, ok := p.ServicesByName[strings.TrimPrefix(name, ".")]; ok {
+            return service
+        }
+    }
+    return nil
+}
+
+// GetMethod get the method by the full name, which is the service full name followed by the method name
+func (p *Packages) GetMethod(name string) *Method {
+    if p != nil {
+        if i := strings.LastIndex(name, "."); i > 0 {
+            return p.GetService(name[:i]).GetMethod(name[i+1:])
+        }
+    }
+    return nil
+}
+
+// GetExtension get the extension by the full name
+func (p *Packages) GetExtension(name string) *Field {
+    if p != nil {
+        if extension, ok := p.ExtensionsByName[strings.TrimPrefix(name, ".")]; ok {
+            return extension
+        }
+    }
+    return nil
+}
+
+// GetExtensionByNumber get the extension of the extendee by the number, the extendee is the full name of the message
+func (p *Packages) GetExtensionByNumber(extendee string, number int32) *Field {
+    if p != nil {
+        if extension, ok := p.ExtensionsByExtendee[strings.TrimPrefix(extendee, ".")][number]; ok {
+            return extension
+        }
+    }
+    return nil
+}
+
+// GetExtensions get all the extensions of the extendee ordered by the number
+func (p *Packages) GetExtensions(extendee string) []*Field {
+    var extensions []*Field
+    if p != nil {
+        for _, extension := range p.ExtensionsByExtendee[strings.TrimPrefix(extendee, ".")] {
+            extensions = append(extensions, extension)
+        }
+        sort.Slice(extensions, func(i, j int) bool {
+            return extensions[i].GetNumber() < extensions[j].GetNumber()
+        })
+    }
+    return extensions
+}
+
 func (p *Packages) AddFile(file *File) *Packages {
     if p != nil && file != nil {
         if _, ok := p.FilesByPath[file.GetName()]; !ok {
             pkg := file.GetPackageName()
             p.Files[pkg] = append(p.Files[pkg], file)
 
+            if file.Packages == nil {
+                file.Packages = p
+            }
+
             p.FilesByPath[file.GetName()] = file
             for _, enum := range file.Enums {
-                p.EnumsByName[enum.GetFullName()] = enum
+                p.addEnum(enum)
             }
             for _, message := range file.Messages {
-                p.MessagesByName[message.GetFullName()] = message
+                p.addMessage(message)
             }
             for _, service := range file.Services {
                 p.ServicesByName[service.GetFullName()] = service
             }
+            for _, extension := range file.Extensions {
+                p.addExtension(extension)
+            }
         }
     }
     return p
 }
 
+func (p *Packages) addMessage(message *Message) {
+    p.MessagesByName[message.GetFullName()] = message
+    for _, enum := range message.Enums {
+        p.addEnum(enum)
+    }
+    for _, msg := range message.Messages {
+        p.addMessage(msg)
+    }
+    for _, extension := range message.Extensions {
+        p.addExtension(extension)
+    }
+}
+
+// addExtension indexes the extension, which is indexed by the extendee only if the extendee is the
+// fully-qualified name, otherwise it will be indexed when resolved by Link.
+func (p *Packages) addExtension(extension *Field) {
+    p.ExtensionsByName[extension.GetFullName()] = extension
+    if extendee := extension.GetExtendee(); strings.HasPrefix(extendee, ".") {
+        p.indexExtension(extendee[1:], extension)
+    }
+}
+
+func (p *Packages) indexExtension(extendee string, extension *Field) {
+    extensions, ok := p.ExtensionsByExtendee[extendee]
+    if !ok {
+        extensions = make(map[int32]*Field)
+        p.ExtensionsByExtendee[extendee] = extensions
+    }
+    extensions[extension.GetNumber()] = extension
+}
+
+func (p *Packages) addEnum(enum *Enum) {
+    p.EnumsByName[enum.GetFullName()] = enum
+}
+
 func (p *Packages) Filter(pkg string, strict bool) []*File {
     var files []*File
     if p != nil {
@@ -0,0 +1,182 @@
+package descriptor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newTestFileProto() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("foo/foo.proto"),
+		Package: proto.String("foo"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Outer"),
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name:     proto.String("Inner"),
+				EnumType: []*descriptorpb.EnumDescriptorProto{{Name: proto.String("Kind")}},
+			}},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("FooService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Get"),
+				InputType:  proto.String(".foo.Outer.Inner"),
+				OutputType: proto.String(".foo.Outer"),
+			}},
+		}},
+	}
+}
+
+func TestPackages_AddFile(t *testing.T) {
+	packages := NewPackages().AddFile(NewFileFrom(newTestFileProto()))
+
+	inner := packages.GetMessage(".foo.Outer.Inner")
+	if assert.NotNil(t, inner) {
+		assert.Equal(t, "foo.Outer.Inner", inner.GetFullName())
+	}
+	assert.Equal(t, inner, packages.GetMessage("foo.Outer.Inner"))
+
+	kind := packages.GetEnum(".foo.Outer.Inner.Kind")
+	if assert.NotNil(t, kind) {
+		assert.Equal(t, "foo.Outer.Inner.Kind", kind.GetFullName())
+	}
+
+	assert.NotNil(t, packages.GetService(".foo.FooService"))
+	method := packages.GetMethod("foo.FooService.Get")
+	if assert.NotNil(t, method) {
+		assert.Equal(t, inner, method.GetInput())
+		assert.Equal(t, "foo.Outer", method.GetOutput().GetFullName())
+	}
+	assert.Nil(t, packages.GetMethod("foo.BarService.Get"))
+}
+
+func TestPackages_Link(t *testing.T) {
+	fd := newTestFileProto()
+	outer := fd.MessageType[0]
+	outer.Field = []*descriptorpb.FieldDescriptorProto{{
+		Name:     proto.String("inner"),
+		Number:   proto.Int32(1),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: proto.String("Inner"),
+	}, {
+		Name:     proto.String("kind"),
+		Number:   proto.Int32(2),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+		TypeName: proto.String(".foo.Outer.Inner.Kind"),
+	}, {
+		Name:     proto.String("missing"),
+		Number:   proto.Int32(3),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: proto.String("Inner.Missing"),
+	}}
+
+	packages := NewPackages().AddFile(NewFileFrom(fd))
+	err := packages.Link()
+	assert.True(t, IsUnresolvedTypeError(err))
+
+	var errs LinkErrors
+	if assert.ErrorAs(t, err, &errs) && assert.Equal(t, 1, len(errs)) {
+		assert.Equal(t, "foo.Outer.missing", errs[0].Element)
+		assert.Equal(t, "Inner.Missing", errs[0].TypeName)
+	}
+
+	message := packages.GetMessage("foo.Outer")
+	assert.Equal(t, packages.GetMessage("foo.Outer.Inner"), message.GetField("inner").Message)
+	assert.Equal(t, packages.GetEnum("foo.Outer.Inner.Kind"), message.GetField("kind").Enum)
+	assert.Nil(t, message.GetField("missing").Message)
+}
+
+func TestPackages_Extensions(t *testing.T) {
+	fd := newTestFileProto()
+	outer := fd.MessageType[0]
+	outer.ExtensionRange = []*descriptorpb.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}}
+	outer.Extension = []*descriptorpb.FieldDescriptorProto{{
+		Name:     proto.String("inner"),
+		Number:   proto.Int32(101),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: proto.String("Inner"),
+		Extendee: proto.String("Outer"),
+	}}
+
+	file := NewFileFrom(fd)
+	file.AppendExtension(NewExtension(file, ".foo.Outer", "name").SetType("String").SetNumber(100))
+	packages := NewPackages().AddFile(file)
+
+	name := packages.GetExtension("foo.name")
+	if assert.NotNil(t, name) {
+		assert.True(t, name.IsExtension())
+		assert.Nil(t, name.Parent)
+	}
+	assert.Equal(t, name, packages.GetExtensionByNumber("foo.Outer", 100))
+	assert.Nil(t, packages.GetExtensionByNumber("foo.Outer", 101))
+
+	assert.NoError(t, packages.Link())
+	inner := packages.GetExtensionByNumber(".foo.Outer", 101)
+	if assert.NotNil(t, inner) {
+		assert.Equal(t, "foo.Outer.inner", inner.GetFullName())
+		assert.Equal(t, "foo.Outer", inner.Extendee.GetFullName())
+		assert.Equal(t, "foo.Outer.Inner", inner.Message.GetFullName())
+	}
+	assert.Equal(t, []*Field{name, inner}, packages.GetExtensions("foo.Outer"))
+	assert.Empty(t, file.Validate().Filter(InvalidExtension))
+
+	file.AppendExtension(NewExtension(file, ".foo.Outer", "id").SetType("Int64").SetNumber(300))
+	diagnostics := file.Validate().Filter(InvalidExtension)
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, "foo.id", diagnostics[0].FullName)
+		assert.Equal(t, []int32{7, 1}, []int32(diagnostics[0].Path))
+	}
+}
+
+func TestPackages_LoadFile(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(structpb.File_google_protobuf_struct_proto),
+	}}
+	data, err := proto.Marshal(set)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "struct.pb")
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+
+	packages := NewPackages()
+	assert.NoError(t, packages.LoadFile(path))
+
+	file := packages.FilesByPath["google/protobuf/struct.proto"]
+	if assert.NotNil(t, file) {
+		assert.Equal(t, packages, file.GetPackages())
+		assert.Len(t, file.Proto.MessageType[0].Field, 1)
+		assert.Len(t, file.Proto.EnumType[0].Value, 1)
+	}
+
+	value := packages.GetMessage("google.protobuf.Value")
+	if assert.NotNil(t, value) {
+		assert.Equal(t, packages.GetEnum("google.protobuf.NullValue"), value.GetField("null_value").Enum)
+		assert.Equal(t, packages.GetMessage("google.protobuf.Struct"), value.GetField("struct_value").Message)
+	}
+	entry := packages.GetMessage("google.protobuf.Struct").GetField("fields").Message
+	if assert.NotNil(t, entry) {
+		assert.True(t, entry.IsMapEntry())
+		assert.Equal(t, value, entry.GetField("value").Message)
+	}
+}
+
+func TestPackages_LoadFileDescriptor(t *testing.T) {
+	packages := NewPackages()
+	assert.NoError(t, packages.LoadFileDescriptor(descriptorpb.File_google_protobuf_descriptor_proto))
+	assert.NotNil(t, packages.GetMessage("google.protobuf.DescriptorProto.ExtensionRange"))
+	assert.NotNil(t, packages.GetEnum("google.protobuf.FieldDescriptorProto.Type"))
+
+	packages, err := NewPackagesFromRegistry(nil)
+	assert.NoError(t, err)
+	field := packages.GetMessage("google.protobuf.Value").GetField("list_value")
+	assert.Equal(t, "google.protobuf.ListValue", field.Message.GetFullName())
+}
@@ -0,0 +1,896 @@
+package descriptor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Printer renders the descriptors as the canonical .proto source text.
+//
+// The layout follows the order used by protoc when it prints a descriptor:
+// options first, then nested messages, enums, fields (with the oneofs inlined),
+// extension ranges, extensions and the reserved ranges and names.
+type Printer struct {
+	Indent string // indentation for each nesting level, default to four spaces
+}
+
+func NewPrinter() *Printer {
+	return &Printer{Indent: "    "}
+}
+
+func (p *Printer) PrintFile(w io.Writer, file *File) error {
+	return p.print(w, func(pp *printer) { pp.file(file) })
+}
+
+func (p *Printer) PrintMessage(w io.Writer, message *Message) error {
+	return p.print(w, func(pp *printer) { pp.message(message) })
+}
+
+func (p *Printer) PrintEnum(w io.Writer, enum *Enum) error {
+	return p.print(w, func(pp *printer) { pp.enum(enum) })
+}
+
+func (p *Printer) PrintService(w io.Writer, service *Service) error {
+	return p.print(w, func(pp *printer) { pp.service(service) })
+}
+
+func (p *Printer) print(w io.Writer, fn func(pp *printer)) error {
+	pp := &printer{indent: p.Indent}
+	if len(pp.indent) == 0 {
+		pp.indent = "    "
+	}
+	fn(pp)
+	_, err := w.Write(pp.buf.Bytes())
+	return err
+}
+
+// Print writes the File as .proto source text to w.
+func (f *File) Print(w io.Writer) error {
+	return NewPrinter().PrintFile(w, f)
+}
+
+// PrintToString returns the .proto source text of the File.
+func (f *File) PrintToString() string {
+	buf := &bytes.Buffer{}
+	_ = f.Print(buf)
+	return buf.String()
+}
+
+// Print writes the Message declaration as .proto source text to w.
+func (m *Message) Print(w io.Writer) error {
+	return NewPrinter().PrintMessage(w, m)
+}
+
+func (m *Message) PrintToString() string {
+	buf := &bytes.Buffer{}
+	_ = m.Print(buf)
+	return buf.String()
+}
+
+// Print writes the Enum declaration as .proto source text to w.
+func (m *Enum) Print(w io.Writer) error {
+	return NewPrinter().PrintEnum(w, m)
+}
+
+func (m *Enum) PrintToString() string {
+	buf := &bytes.Buffer{}
+	_ = m.Print(buf)
+	return buf.String()
+}
+
+// Print writes the Service declaration as .proto source text to w.
+func (s *Service) Print(w io.Writer) error {
+	return NewPrinter().PrintService(w, s)
+}
+
+func (s *Service) PrintToString() string {
+	buf := &bytes.Buffer{}
+	_ = s.Print(buf)
+	return buf.String()
+}
+
+type printer struct {
+	buf    bytes.Buffer
+	indent string
+	level  int
+	line   int
+
+	spans  map[*Descriptor][]int32 // records the spans of the declarations if not nil
+	blocks []*Descriptor
+	starts [][2]int
+
+	source *File
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	text := strings.Repeat(p.indent, p.level) + fmt.Sprintf(format, args...)
+	p.line += strings.Count(text, "\n")
+	p.buf.WriteString(text)
+}
+
+func (p *printer) newline() {
+	p.line++
+	p.buf.WriteByte('\n')
+}
+
+func (p *printer) column() int {
+	return len(p.indent) * p.level
+}
+
+func (p *printer) span(d *Descriptor, startLine, startCol, endLine, endCol int) {
+	if p.spans == nil || d == nil {
+		return
+	}
+	if startLine == endLine {
+		p.spans[d] = []int32{int32(startLine), int32(startCol), int32(endCol)}
+	} else {
+		p.spans[d] = []int32{int32(startLine), int32(startCol), int32(endLine), int32(endCol)}
+	}
+}
+
+func (p *printer) in()  { p.level++ }
+func (p *printer) out() { p.level-- }
+
+func (p *printer) comments(comments Comments) {
+	if len(comments) == 0 {
+		return
+	}
+	for _, line := range strings.Split(comments.String(), "\n") {
+		if len(line) > 0 {
+			p.printf("%s\n", line)
+		}
+	}
+}
+
+func (p *printer) leadingComments(d *Descriptor) {
+	for _, detached := range d.Comments.LeadingDetached {
+		p.comments(detached)
+		p.newline()
+	}
+	p.comments(d.Comments.Leading)
+}
+
+// declaration prints a single line declaration with its trailing comments.
+func (p *printer) declaration(d *Descriptor, format string, args ...interface{}) {
+	p.leadingComments(d)
+	line := fmt.Sprintf(format, args...)
+	p.span(d, p.line, p.column(), p.line, p.column()+len(line))
+	p.trailingComments(line, d.Comments.Trailing, false)
+}
+
+// block opens a block declaration, the trailing comments go after the opening brace.
+func (p *printer) block(d *Descriptor, format string, args ...interface{}) {
+	p.leadingComments(d)
+	p.blocks = append(p.blocks, d)
+	p.starts = append(p.starts, [2]int{p.line, p.column()})
+	p.trailingComments(fmt.Sprintf(format+" {", args...), d.Comments.Trailing, true)
+}
+
+// trailingComments prints the line ended with the trailing comments. The single line comments stay on the
+// same line, the multi-line ones follow the line with an empty line after them, otherwise they would be
+// attached to the next declaration as its leading comments when parsed.
+func (p *printer) trailingComments(line string, trailing Comments, block bool) {
+	text := strings.TrimSuffix(string(trailing), "\n")
+	if len(trailing) > 0 && !strings.Contains(text, "\n") {
+		p.printf("%s //%s\n", line, text)
+	} else {
+		p.printf("%s\n", line)
+	}
+	if block {
+		p.in()
+	}
+	if strings.Contains(text, "\n") {
+		p.comments(trailing)
+		p.newline()
+	}
+}
+
+func (p *printer) end() {
+	p.out()
+	if n := len(p.blocks); n > 0 {
+		start := p.starts[n-1]
+		p.span(p.blocks[n-1], start[0], start[1], p.line, p.column()+1)
+		p.blocks, p.starts = p.blocks[:n-1], p.starts[:n-1]
+	}
+	p.printf("}\n")
+}
+
+// separator inserts an empty line between the top level or nested declarations
+func (p *printer) separator(count *int) {
+	if *count > 0 {
+		p.newline()
+	}
+	*count++
+}
+
+func (p *printer) syntax() string {
+	return p.source.GetSyntax()
+}
+
+func (p *printer) file(file *File) {
+	if file == nil || file.Proto == nil {
+		return
+	}
+	p.source = file
+	fd := file.Proto
+
+	if fd.GetSyntax() == EditionsSyntax {
+		p.printf("edition = %s;\n", quote(fd.GetEdition()))
+	} else if len(fd.GetSyntax()) > 0 {
+		p.printf("syntax = %s;\n", quote(fd.GetSyntax()))
+	} else {
+		p.printf("syntax = %s;\n", quote(Proto2Syntax))
+	}
+
+	if len(fd.GetPackage()) > 0 {
+		p.newline()
+		p.printf("package %s;\n", fd.GetPackage())
+	}
+
+	if len(fd.Dependency) > 0 {
+		p.newline()
+		public := make(map[int32]bool)
+		weak := make(map[int32]bool)
+		for _, i := range fd.PublicDependency {
+			public[i] = true
+		}
+		for _, i := range fd.WeakDependency {
+			weak[i] = true
+		}
+		for i, dep := range fd.Dependency {
+			modifier := ""
+			if public[int32(i)] {
+				modifier = "public "
+			} else if weak[int32(i)] {
+				modifier = "weak "
+			}
+			p.printf("import %s%s;\n", modifier, quote(dep))
+		}
+	}
+
+	if options := p.options(fd.GetOptions()); len(options) > 0 {
+		p.newline()
+		for _, option := range options {
+			p.printf("option %s;\n", option)
+		}
+	}
+
+	for _, enum := range file.Enums {
+		p.newline()
+		p.enum(enum)
+	}
+	for _, message := range file.Messages {
+		p.newline()
+		p.message(message)
+	}
+	for _, service := range file.Services {
+		p.newline()
+		p.service(service)
+	}
+	if len(file.Extensions) > 0 {
+		p.newline()
+		p.extensions(nil, file.Extensions)
+	}
+}
+
+func (p *printer) message(message *Message) {
+	if message == nil || message.Proto == nil {
+		return
+	}
+	if p.source == nil {
+		p.source = message.File
+	}
+	md := message.Proto
+
+	p.block(&message.Descriptor, "message %s", md.GetName())
+	p.messageBody(message)
+	p.end()
+}
+
+func (p *printer) messageBody(message *Message) {
+	md := message.Proto
+	count := 0
+
+	if options := p.options(md.GetOptions()); len(options) > 0 {
+		p.separator(&count)
+		for _, option := range options {
+			p.printf("option %s;\n", option)
+		}
+	}
+
+	for _, msg := range message.Messages {
+		if msg.IsMapEntry() || p.isGroupMessage(message, msg) {
+			continue
+		}
+		p.separator(&count)
+		p.message(msg)
+	}
+
+	for _, enum := range message.Enums {
+		p.separator(&count)
+		p.enum(enum)
+	}
+
+	if len(message.Fields) > 0 || len(message.Oneofs) > 0 {
+		p.separator(&count)
+		printed := make(map[*Oneof]bool)
+		for _, field := range message.Fields {
+			if oneof := field.Oneof; oneof != nil && !oneof.IsSynthetic() {
+				if !printed[oneof] {
+					printed[oneof] = true
+					p.oneof(oneof)
+				}
+				continue
+			}
+			p.field(message, field.Proto, &field.Descriptor, false)
+		}
+		for _, oneof := range message.Oneofs {
+			if !printed[oneof] && !oneof.IsSynthetic() {
+				printed[oneof] = true
+				p.oneof(oneof)
+			}
+		}
+	}
+
+	if len(md.ExtensionRange) > 0 {
+		p.separator(&count)
+		for _, r := range md.ExtensionRange {
+			line := "extensions " + formatRange(r.GetStart(), r.GetEnd()-1, MaxFieldNumber)
+			if options := p.options(r.GetOptions()); len(options) > 0 {
+				line += " [" + strings.Join(options, ", ") + "]"
+			}
+			p.printf("%s;\n", line)
+		}
+	}
+
+	if len(message.Extensions) > 0 {
+		p.separator(&count)
+		p.extensions(message, message.Extensions)
+	}
+
+	if len(md.ReservedRange) > 0 || len(md.ReservedName) > 0 {
+		p.separator(&count)
+		if len(md.ReservedRange) > 0 {
+			var ranges []string
+			for _, r := range md.ReservedRange {
+				ranges = append(ranges, formatRange(r.GetStart(), r.GetEnd()-1, MaxFieldNumber))
+			}
+			p.printf("reserved %s;\n", strings.Join(ranges, ", "))
+		}
+		if len(md.ReservedName) > 0 {
+			p.printf("reserved %s;\n", p.reservedNames(md.ReservedName))
+		}
+	}
+}
+
+func (p *printer) reservedNames(names []string) string {
+	var quoted []string
+	for _, name := range names {
+		if p.syntax() == EditionsSyntax {
+			quoted = append(quoted, name)
+		} else {
+			quoted = append(quoted, quote(name))
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (p *printer) oneof(oneof *Oneof) {
+	p.block(&oneof.Descriptor, "oneof %s", oneof.GetName())
+	for _, option := range p.options(oneof.Proto.GetOptions()) {
+		p.printf("option %s;\n", option)
+	}
+	for _, field := range oneof.Fields {
+		p.field(oneof.Parent, field.Proto, &field.Descriptor, true)
+	}
+	p.end()
+}
+
+func (p *printer) extensions(scope *Message, extensions []*Field) {
+	var extendees []string
+	grouped := make(map[string][]*Field)
+	for _, ext := range extensions {
+		extendee := ext.Proto.GetExtendee()
+		if _, ok := grouped[extendee]; !ok {
+			extendees = append(extendees, extendee)
+		}
+		grouped[extendee] = append(grouped[extendee], ext)
+	}
+
+	for i, extendee := range extendees {
+		if i > 0 {
+			p.newline()
+		}
+		p.printf("extend %s {\n", p.typeName(scope, extendee))
+		p.in()
+		for _, ext := range grouped[extendee] {
+			p.field(scope, ext.Proto, &ext.Descriptor, false)
+		}
+		p.out()
+		p.printf("}\n")
+	}
+}
+
+func (p *printer) field(scope *Message, fd *descriptorpb.FieldDescriptorProto, d *Descriptor, inOneof bool) {
+	label := ""
+	if !inOneof {
+		label = p.label(fd)
+	}
+
+	var options []string
+	if fd.DefaultValue != nil {
+		options = append(options, "default = "+formatDefaultValue(fd))
+	}
+	if fd.JsonName != nil && fd.GetJsonName() != JsonName(fd.GetName()) {
+		options = append(options, "json_name = "+quote(fd.GetJsonName()))
+	}
+	options = append(options, p.options(fd.GetOptions())...)
+	suffix := ""
+	if len(options) > 0 {
+		suffix = " [" + strings.Join(options, ", ") + "]"
+	}
+
+	entry := mapEntryOf(scope, nil, fd)
+	if key, value := entry.GetFieldByNumber(1), entry.GetFieldByNumber(2); key != nil && value != nil {
+		p.declaration(d, "map<%s, %s> %s = %d%s;",
+			p.fieldType(scope, key.Proto), p.fieldType(scope, value.Proto), fd.GetName(), fd.GetNumber(), suffix)
+		return
+	}
+
+	if fd.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP {
+		if group := p.groupMessage(scope, fd); group != nil {
+			p.block(d, "%sgroup %s = %d%s", label, group.GetName(), fd.GetNumber(), suffix)
+			p.messageBody(group)
+			p.end()
+			return
+		}
+	}
+
+	p.declaration(d, "%s%s %s = %d%s;", label, p.fieldType(scope, fd), fd.GetName(), fd.GetNumber(), suffix)
+}
+
+func (p *printer) label(fd *descriptorpb.FieldDescriptorProto) string {
+	switch fd.GetLabel() {
+	case descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+		return "repeated "
+	case descriptorpb.FieldDescriptorProto_LABEL_REQUIRED:
+		if p.syntax() == Proto2Syntax {
+			return "required "
+		}
+	case descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL:
+		switch p.syntax() {
+		case Proto2Syntax:
+			return "optional "
+		case Proto3Syntax:
+			if fd.GetProto3Optional() {
+				return "optional "
+			}
+		}
+	}
+	return ""
+}
+
+func (p *printer) fieldType(scope *Message, fd *descriptorpb.FieldDescriptorProto) string {
+	if fd == nil {
+		return ""
+	}
+	switch fd.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_ENUM,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return p.typeName(scope, fd.GetTypeName())
+	}
+	if fd.Type == nil {
+		return p.typeName(scope, fd.GetTypeName())
+	}
+	if name, ok := fieldDescriptorProtoTypeName[fd.GetType()]; ok {
+		return name
+	}
+	return strings.ToLower(strings.TrimPrefix(fd.GetType().String(), "TYPE_"))
+}
+
+// typeName trims the package of the fully-qualified type name when the short one
+// will not be shadowed by any nested declaration in the current scope.
+func (p *printer) typeName(scope *Message, name string) string {
+	if !strings.HasPrefix(name, ".") {
+		return name
+	}
+
+	relative := name[1:]
+	if pkg := p.source.GetPackageName(); len(pkg) > 0 {
+		if !strings.HasPrefix(relative, pkg+".") {
+			return name
+		}
+		relative = relative[len(pkg)+1:]
+	}
+
+	first := relative
+	if i := strings.Index(relative, "."); i >= 0 {
+		first = relative[:i]
+	}
+	for m := scope; m != nil; m = m.Parent {
+		if m.IsMessageExist(first) || m.IsEnumExist(first) || m.IsFieldExist(first) {
+			return name
+		}
+	}
+	return relative
+}
+
+func (p *printer) nestedMessage(scope *Message, typeName string) *Message {
+	if scope == nil {
+		return nil
+	}
+	name := typeName
+	if i := strings.LastIndex(typeName, "."); i >= 0 {
+		name = typeName[i+1:]
+	}
+	return scope.GetMessage(name)
+}
+
+func (p *printer) groupMessage(scope *Message, fd *descriptorpb.FieldDescriptorProto) *Message {
+	if msg := p.nestedMessage(scope, fd.GetTypeName()); msg != nil && strings.EqualFold(msg.GetName(), fd.GetName()) {
+		return msg
+	}
+	return nil
+}
+
+func (p *printer) isGroupMessage(scope *Message, msg *Message) bool {
+	for _, field := range scope.Fields {
+		if field.Proto.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP && p.groupMessage(scope, field.Proto) == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *printer) enum(enum *Enum) {
+	if enum == nil || enum.Proto == nil {
+		return
+	}
+	if p.source == nil {
+		p.source = enum.File
+	}
+	ed := enum.Proto
+
+	p.block(&enum.Descriptor, "enum %s", ed.GetName())
+	for _, option := range p.options(ed.GetOptions()) {
+		p.printf("option %s;\n", option)
+	}
+	for _, value := range enum.Values {
+		suffix := ""
+		if options := p.options(value.Proto.GetOptions()); len(options) > 0 {
+			suffix = " [" + strings.Join(options, ", ") + "]"
+		}
+		p.declaration(&value.Descriptor, "%s = %d%s;", value.GetName(), value.GetNumber(), suffix)
+	}
+	if len(ed.ReservedRange) > 0 {
+		var ranges []string
+		for _, r := range ed.ReservedRange {
+			ranges = append(ranges, formatRange(r.GetStart(), r.GetEnd(), MaxEnumNumber))
+		}
+		p.printf("reserved %s;\n", strings.Join(ranges, ", "))
+	}
+	if len(ed.ReservedName) > 0 {
+		p.printf("reserved %s;\n", p.reservedNames(ed.ReservedName))
+	}
+	p.end()
+}
+
+func (p *printer) service(service *Service) {
+	if service == nil || service.Proto == nil {
+		return
+	}
+	if p.source == nil {
+		p.source = service.File
+	}
+
+	p.block(&service.Descriptor, "service %s", service.GetName())
+	for _, option := range p.options(service.Proto.GetOptions()) {
+		p.printf("option %s;\n", option)
+	}
+	for _, method := range service.Methods {
+		md := method.Proto
+		input := p.typeName(nil, md.GetInputType())
+		if md.GetClientStreaming() {
+			input = "stream " + input
+		}
+		output := p.typeName(nil, md.GetOutputType())
+		if md.GetServerStreaming() {
+			output = "stream " + output
+		}
+
+		signature := fmt.Sprintf("rpc %s(%s) returns (%s)", md.GetName(), input, output)
+		if options := p.options(md.GetOptions()); len(options) > 0 {
+			p.block(&method.Descriptor, "%s", signature)
+			for _, option := range options {
+				p.printf("option %s;\n", option)
+			}
+			p.end()
+		} else {
+			p.declaration(&method.Descriptor, "%s;", signature)
+		}
+	}
+	p.end()
+}
+
+// options formats all the set options including the registered extensions,
+// ordered by the field number and followed by the extensions ordered by the full name,
+// then the uninterpreted options in the declaration order.
+func (p *printer) options(options proto.Message) []string {
+	if options == nil {
+		return nil
+	}
+	msg := options.ProtoReflect()
+	if !msg.IsValid() {
+		return nil
+	}
+
+	var fields []protoreflect.FieldDescriptor
+	var uninterpreted protoreflect.List
+	msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if !fd.IsExtension() {
+			switch fd.Name() {
+			case "uninterpreted_option":
+				uninterpreted = value.List()
+				return true
+			case "map_entry":
+				return true
+			}
+		}
+		fields = append(fields, fd)
+		return true
+	})
+	sortFieldDescriptors(fields)
+
+	var values []string
+	for _, fd := range fields {
+		name := optionName(fd)
+		value := msg.Get(fd)
+		if fd.IsList() {
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				values = append(values, name+" = "+formatValue(fd, list.Get(i), p.indent, p.level))
+			}
+		} else {
+			values = append(values, name+" = "+formatValue(fd, value, p.indent, p.level))
+		}
+	}
+	if uninterpreted != nil {
+		for i := 0; i < uninterpreted.Len(); i++ {
+			values = append(values, formatUninterpretedOption(toUninterpretedOption(uninterpreted.Get(i).Message())))
+		}
+	}
+	return values
+}
+
+func toUninterpretedOption(msg protoreflect.Message) *descriptorpb.UninterpretedOption {
+	if option, ok := msg.Interface().(*descriptorpb.UninterpretedOption); ok {
+		return option
+	}
+	option := &descriptorpb.UninterpretedOption{}
+	if data, err := proto.Marshal(msg.Interface()); err == nil {
+		_ = proto.Unmarshal(data, option)
+	}
+	return option
+}
+
+// formatUninterpretedOption formats the option not resolved by the parser back in the source form
+func formatUninterpretedOption(option *descriptorpb.UninterpretedOption) string {
+	var names []string
+	for _, part := range option.GetName() {
+		if part.GetIsExtension() {
+			names = append(names, "("+part.GetNamePart()+")")
+		} else {
+			names = append(names, part.GetNamePart())
+		}
+	}
+
+	var value string
+	switch {
+	case option.IdentifierValue != nil:
+		value = option.GetIdentifierValue()
+	case option.PositiveIntValue != nil:
+		value = strconv.FormatUint(option.GetPositiveIntValue(), 10)
+	case option.NegativeIntValue != nil:
+		value = strconv.FormatInt(option.GetNegativeIntValue(), 10)
+	case option.DoubleValue != nil:
+		value = formatFloat(option.GetDoubleValue())
+	case option.StringValue != nil:
+		value = quoteBytes(option.GetStringValue())
+	case option.AggregateValue != nil:
+		value = "{ " + option.GetAggregateValue() + " }"
+	}
+	return strings.Join(names, ".") + " = " + value
+}
+
+func sortFieldDescriptors(fields []protoreflect.FieldDescriptor) {
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].IsExtension() != fields[j].IsExtension() {
+			return !fields[i].IsExtension()
+		}
+		if fields[i].IsExtension() {
+			return fields[i].FullName() < fields[j].FullName()
+		}
+		return fields[i].Number() < fields[j].Number()
+	})
+}
+
+func optionName(fd protoreflect.FieldDescriptor) string {
+	if fd.IsExtension() {
+		return "(" + string(fd.FullName()) + ")"
+	}
+	return string(fd.Name())
+}
+
+func formatValue(fd protoreflect.FieldDescriptor, value protoreflect.Value, indent string, level int) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return formatFloat(value.Float())
+	case protoreflect.StringKind:
+		return quote(value.String())
+	case protoreflect.BytesKind:
+		return quoteBytes(value.Bytes())
+	case protoreflect.EnumKind:
+		if v := fd.Enum().Values().ByNumber(value.Enum()); v != nil {
+			return string(v.Name())
+		}
+		return strconv.FormatInt(int64(value.Enum()), 10)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return formatAggregate(value.Message(), indent, level)
+	}
+	return value.String()
+}
+
+// formatAggregate formats the message value in the protobuf text format with a stable layout.
+func formatAggregate(msg protoreflect.Message, indent string, level int) string {
+	var fields []protoreflect.FieldDescriptor
+	msg.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+	if len(fields) == 0 {
+		return "{}"
+	}
+	sortFieldDescriptors(fields)
+
+	prefix := strings.Repeat(indent, level+1)
+	b := &strings.Builder{}
+	b.WriteString("{\n")
+	for _, fd := range fields {
+		name := string(fd.Name())
+		if fd.IsExtension() {
+			name = "[" + string(fd.FullName()) + "]"
+		}
+		value := msg.Get(fd)
+		switch {
+		case fd.IsList():
+			list := value.List()
+			var values []string
+			for i := 0; i < list.Len(); i++ {
+				values = append(values, formatValue(fd, list.Get(i), indent, level+1))
+			}
+			b.WriteString(prefix + name + ": [" + strings.Join(values, ", ") + "]\n")
+		case fd.IsMap():
+			value.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				b.WriteString(prefix + name + " {\n")
+				b.WriteString(prefix + indent + "key: " + formatValue(fd.MapKey(), k.Value(), indent, level+2) + "\n")
+				b.WriteString(prefix + indent + "value: " + formatValue(fd.MapValue(), v, indent, level+2) + "\n")
+				b.WriteString(prefix + "}\n")
+				return true
+			})
+		default:
+			b.WriteString(prefix + name + ": " + formatValue(fd, value, indent, level+1) + "\n")
+		}
+	}
+	b.WriteString(strings.Repeat(indent, level) + "}")
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func formatDefaultValue(fd *descriptorpb.FieldDescriptorProto) string {
+	value := fd.GetDefaultValue()
+	switch fd.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING:
+		return quote(value)
+	case descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		// the default value of bytes has already been c-escaped by protoc
+		return `"` + value + `"`
+	}
+	return value
+}
+
+func formatRange(start int32, end int32, max int32) string {
+	switch {
+	case start == end:
+		return strconv.Itoa(int(start))
+	case end >= max:
+		return strconv.Itoa(int(start)) + " to max"
+	}
+	return strconv.Itoa(int(start)) + " to " + strconv.Itoa(int(end))
+}
+
+// quote returns the string literal that could be parsed by protoc,
+// escaping the non-printable bytes in the octal format.
+func quote(s string) string {
+	return escape(s, false)
+}
+
+func quoteBytes(bs []byte) string {
+	return escape(string(bs), true)
+}
+
+func escape(s string, binary bool) string {
+	b := &strings.Builder{}
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if c >= 0x20 && c < 0x7f || c >= 0x80 && !binary {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(b, `\%03o`, c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// JsonName returns the default json name of the field as protoc does.
+func JsonName(name string) string {
+	b := &strings.Builder{}
+	upper := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteByte(c)
+	}
+	return b.String()
+}
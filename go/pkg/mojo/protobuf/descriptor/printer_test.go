@@ -0,0 +1,105 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/mojo-lang/core/go/pkg/mojo"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFile_Print(t *testing.T) {
+	file := NewFileWithName("foo.proto", "mojo.foo")
+	file.AppendDependency("mojo/mojo.proto")
+	file.GetOptions().GoPackage = &[]string{"github.com/mojo-lang/foo"}[0]
+
+	enum := NewEnum(file).SetName("Kind")
+	enum.AppendValueWith("KIND_UNSPECIFIED", 0).AppendValueWith("KIND_BAR", 1)
+	enum.Values[1].Comments.Trailing = " the bar kind"
+	file.AppendEnum(enum)
+
+	message := NewMessage(file).SetName("Foo")
+	message.Comments.Leading = " Foo is the foo message\n second line\n"
+	message.AppendField(NewField(message, "name").SetType("String").SetNumber(1).SetOption(mojo.E_Alias, "n"))
+	message.AppendField(NewEnumField(message, "kind", enum).SetTypeName(".mojo.foo.Kind").SetNumber(2))
+	message.AppendOneofWith("value")
+	message.AppendField(NewField(message, "int_val").SetType("Int32").SetNumber(3))
+	message.GetOneof("value").AppendField(message.Fields[2])
+	file.AppendMessage(message)
+
+	expected := `syntax = "proto3";
+
+package mojo.foo;
+
+import "mojo/mojo.proto";
+
+option go_package = "github.com/mojo-lang/foo";
+
+enum Kind {
+    KIND_UNSPECIFIED = 0;
+    KIND_BAR = 1; // the bar kind
+}
+
+// Foo is the foo message
+// second line
+message Foo {
+    string name = 1 [(mojo.alias) = "n"];
+    Kind kind = 2;
+    oneof value {
+        int32 int_val = 3;
+    }
+}
+`
+	assert.Equal(t, expected, file.PrintToString())
+}
+
+func TestMessage_Print(t *testing.T) {
+	file := NewFileFrom(protodesc.ToFileDescriptorProto(structpb.File_google_protobuf_struct_proto))
+
+	expected := `message Struct {
+    map<string, Value> fields = 1;
+}
+`
+	assert.Equal(t, expected, file.GetMessage("Struct").PrintToString())
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"a\"b\\c\n\001"`, quote("a\"b\\c\n\x01"))
+	assert.Equal(t, `"\377"`, quoteBytes([]byte{0xff}))
+}
+
+func TestFile_Print_UninterpretedOptions(t *testing.T) {
+	file := NewFileWithName("foo.proto", "foo")
+	message := NewMessage(file).SetName("Foo")
+	field := NewField(message, "a").SetType("Int32").SetNumber(1)
+	field.Proto.Options = &descriptorpb.FieldOptions{
+		Deprecated: proto.Bool(true),
+		UninterpretedOption: []*descriptorpb.UninterpretedOption{{
+			Name: []*descriptorpb.UninterpretedOption_NamePart{
+				{NamePart: proto.String("features"), IsExtension: proto.Bool(false)},
+				{NamePart: proto.String("field_presence"), IsExtension: proto.Bool(false)},
+			},
+			IdentifierValue: proto.String("IMPLICIT"),
+		}, {
+			Name: []*descriptorpb.UninterpretedOption_NamePart{
+				{NamePart: proto.String("bar.baz"), IsExtension: proto.Bool(true)},
+				{NamePart: proto.String("qux"), IsExtension: proto.Bool(false)},
+			},
+			AggregateValue: proto.String("a: 1"),
+		}, {
+			Name:             []*descriptorpb.UninterpretedOption_NamePart{{NamePart: proto.String("bar.size"), IsExtension: proto.Bool(true)}},
+			NegativeIntValue: proto.Int64(-2),
+		}},
+	}
+	message.AppendField(field)
+	file.AppendMessage(message)
+
+	expected := `message Foo {
+    int32 a = 1 [deprecated = true, features.field_presence = IMPLICIT, (bar.baz).qux = { a: 1 }, (bar.size) = -2];
+}
+`
+	assert.Equal(t, expected, message.PrintToString())
+}
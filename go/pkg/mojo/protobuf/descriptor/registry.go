@@ -0,0 +1,244 @@
+package descriptor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// NewFileFromDescriptor wraps the compiled protoreflect.FileDescriptor, without its dependencies.
+func NewFileFromDescriptor(fd protoreflect.FileDescriptor) *File {
+	if fd == nil {
+		return nil
+	}
+	return NewFileFrom(protodesc.ToFileDescriptorProto(fd))
+}
+
+// NewPackagesFromRegistry builds the Packages from all the files registered in the registry,
+// protoregistry.GlobalFiles will be used if the files is nil.
+func NewPackagesFromRegistry(files *protoregistry.Files) (*Packages, error) {
+	packages := NewPackages()
+	if err := packages.LoadRegistry(files); err != nil {
+		return packages, err
+	}
+	return packages, nil
+}
+
+// LoadFileDescriptor adds the file descriptor and all its transitive dependencies,
+// then links the types and methods.
+func (p *Packages) LoadFileDescriptor(fd protoreflect.FileDescriptor) error {
+	if p == nil || fd == nil {
+		return nil
+	}
+
+	p.addFileDescriptor(fd)
+	return p.Link()
+}
+
+// LoadRegistry adds all the files in the registry, protoregistry.GlobalFiles will be used if the files is nil.
+func (p *Packages) LoadRegistry(files *protoregistry.Files) error {
+	if p == nil {
+		return nil
+	}
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		p.addFileDescriptor(fd)
+		return true
+	})
+	return p.Link()
+}
+
+// addFileDescriptor adds the dependencies before the file itself
+func (p *Packages) addFileDescriptor(fd protoreflect.FileDescriptor) {
+	if fd.IsPlaceholder() {
+		return
+	}
+	if _, ok := p.FilesByPath[fd.Path()]; ok {
+		return
+	}
+
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if dep := imports.Get(i).FileDescriptor; dep != nil {
+			p.addFileDescriptor(dep)
+		}
+	}
+
+	file := NewFileFromDescriptor(fd)
+	file.Packages = p
+	p.AddFile(file)
+}
+
+// DescriptorError reports the error when converting the File into the protoreflect.FileDescriptor
+type DescriptorError struct {
+	File       *File
+	FullName   string      // full name of the element mentioned by the error, if any
+	Descriptor *Descriptor // the wrapper of the element mentioned by the error, nil if not found
+	Err        error
+}
+
+func (e *DescriptorError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File.GetName(), e.Err.Error())
+}
+
+func (e *DescriptorError) Unwrap() error {
+	return e.Err
+}
+
+// ToRegistry converts all the files into protoreflect descriptors in the dependency order,
+// the dependencies not in the Packages will be looked up in protoregistry.GlobalFiles.
+//
+// The returned map holds the converted file descriptors of the Packages keyed by the file name.
+func (p *Packages) ToRegistry() (*protoregistry.Files, map[string]protoreflect.FileDescriptor, error) {
+	files := &protoregistry.Files{}
+	descriptors := make(map[string]protoreflect.FileDescriptor)
+	if p == nil {
+		return files, descriptors, nil
+	}
+
+	for _, file := range p.sortedFiles() {
+		fd, err := p.RegisterFile(files, file)
+		if err != nil {
+			return files, descriptors, err
+		}
+		descriptors[file.GetName()] = fd
+	}
+	return files, descriptors, nil
+}
+
+// RegisterFile converts the file and registers it with all its dependencies into the files
+// if not registered yet, the dependencies not in the Packages will be looked up in protoregistry.GlobalFiles.
+func (p *Packages) RegisterFile(files *protoregistry.Files, file *File) (protoreflect.FileDescriptor, error) {
+	return p.registerFile(files, file, make(map[string]bool))
+}
+
+func (p *Packages) registerFile(files *protoregistry.Files, file *File, visiting map[string]bool) (protoreflect.FileDescriptor, error) {
+	name := file.GetName()
+	if fd, err := files.FindFileByPath(name); err == nil {
+		return fd, nil
+	}
+	if visiting[name] {
+		return nil, &DescriptorError{File: file, Err: fmt.Errorf("import cycle found in %q", name)}
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	for _, dep := range file.GetDependencies() {
+		if d, ok := p.FilesByPath[dep]; ok {
+			if _, err := p.registerFile(files, d, visiting); err != nil {
+				return nil, err
+			}
+		} else if err := registerGlobalFile(files, dep); err != nil {
+			return nil, &DescriptorError{File: file, FullName: dep, Err: err}
+		}
+	}
+
+	fd, err := protodesc.NewFile(file.Proto, files)
+	if err != nil {
+		return nil, p.newDescriptorError(file, err)
+	}
+	if err = files.RegisterFile(fd); err != nil {
+		return nil, p.newDescriptorError(file, err)
+	}
+	return fd, nil
+}
+
+// ToFileDescriptor converts the file into the protoreflect.FileDescriptor, only the file and its transitive
+// dependencies are converted, which are resolved in the Packages of the file, and then protoregistry.GlobalFiles.
+func (f *File) ToFileDescriptor() (protoreflect.FileDescriptor, error) {
+	if f == nil || f.Proto == nil {
+		return nil, nil
+	}
+
+	packages := f.Packages
+	if packages == nil {
+		packages = NewPackages()
+	}
+	return packages.RegisterFile(&protoregistry.Files{}, f)
+}
+
+func registerGlobalFile(files *protoregistry.Files, path string) error {
+	if _, err := files.FindFileByPath(path); err == nil {
+		return nil
+	}
+
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+	if err != nil {
+		return err
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err = registerGlobalFile(files, imports.Get(i).Path()); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+var quotedNamePattern = regexp.MustCompile(`"([^"]+)"`)
+
+// newDescriptorError maps the first name quoted in the protodesc error back to the wrapper, which relies on
+// protodesc quoting the full names in its errors, like `message field "foo.Foo.value" cannot resolve type`.
+func (p *Packages) newDescriptorError(file *File, err error) *DescriptorError {
+	e := &DescriptorError{File: file, Err: err}
+	for _, match := range quotedNamePattern.FindAllStringSubmatch(err.Error(), -1) {
+		if d := p.FindDescriptor(match[1]); d != nil {
+			e.FullName = strings.TrimPrefix(match[1], ".")
+			e.Descriptor = d
+			break
+		}
+	}
+	return e
+}
+
+// FindDescriptor finds the descriptor of the message, enum, service, method, field, oneof or enum value by the full name
+func (p *Packages) FindDescriptor(fullName string) *Descriptor {
+	if p == nil {
+		return nil
+	}
+
+	fullName = strings.TrimPrefix(fullName, ".")
+	if message := p.GetMessage(fullName); message != nil {
+		return &message.Descriptor
+	}
+	if enum := p.GetEnum(fullName); enum != nil {
+		return &enum.Descriptor
+	}
+	if service := p.GetService(fullName); service != nil {
+		return &service.Descriptor
+	}
+	if method := p.GetMethod(fullName); method != nil {
+		return &method.Descriptor
+	}
+
+	i := strings.LastIndex(fullName, ".")
+	if i < 0 {
+		return nil
+	}
+	scope, name := fullName[:i], fullName[i+1:]
+	if message := p.GetMessage(scope); message != nil {
+		if field := message.GetField(name); field != nil {
+			return &field.Descriptor
+		}
+		if oneof := message.GetOneof(name); oneof != nil {
+			return &oneof.Descriptor
+		}
+	}
+
+	// the enum values are siblings of their enum type
+	for _, enum := range p.EnumsByName {
+		if enum.Parent.GetFullName() == scope || (enum.Parent == nil && enum.GetPackageName() == scope) {
+			if value := enum.GetValue(name); value != nil {
+				return &value.Descriptor
+			}
+		}
+	}
+	return nil
+}
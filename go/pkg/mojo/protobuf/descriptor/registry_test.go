@@ -0,0 +1,81 @@
+package descriptor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func newTestFile() *File {
+	file := NewFileWithName("foo/foo.proto", "foo")
+	file.AppendDependency("google/protobuf/struct.proto")
+
+	message := NewMessage(file).SetName("Foo")
+	message.AppendField(NewField(message, "name").SetType("String").SetNumber(1))
+	message.AppendField(NewField(message, "value").SetTypeName(".google.protobuf.Value").SetNumber(2))
+	message.Fields[1].Proto.Type = &messageType
+	file.AppendMessage(message)
+	return file
+}
+
+func TestFile_ToFileDescriptor(t *testing.T) {
+	fd, err := newTestFile().ToFileDescriptor()
+	assert.NoError(t, err)
+	if assert.NotNil(t, fd) {
+		md := fd.Messages().ByName("Foo")
+		if assert.NotNil(t, md) {
+			msg := dynamicpb.NewMessage(md)
+			msg.Set(md.Fields().ByName("name"), protoreflect.ValueOfString("foo"))
+			assert.Equal(t, "foo", msg.Get(md.Fields().ByName("name")).String())
+		}
+	}
+}
+
+func TestPackages_ToRegistry(t *testing.T) {
+	file := newTestFile()
+	file.GetMessage("Foo").GetField("value").SetTypeName(".foo.Missing")
+
+	packages := NewPackages().AddFile(file)
+	_, _, err := packages.ToRegistry()
+
+	var e *DescriptorError
+	if assert.True(t, errors.As(err, &e)) {
+		assert.Equal(t, file, e.File)
+		assert.Equal(t, "foo.Foo.value", e.FullName)
+		assert.Equal(t, &file.GetMessage("Foo").GetField("value").Descriptor, e.Descriptor)
+	}
+}
+
+func TestFile_ToFileDescriptor_Closure(t *testing.T) {
+	file := newTestFile()
+	broken := NewFileWithName("bar/bar.proto", "bar")
+	broken.AppendMessage(NewMessage(broken).SetName("Bar"))
+	broken.AppendMessage(NewMessage(broken).SetName("Bar"))
+
+	NewPackages().AddFile(broken).AddFile(file)
+	fd, err := file.ToFileDescriptor()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo/foo.proto", fd.Path())
+
+	_, err = broken.ToFileDescriptor()
+	assert.Error(t, err)
+}
+
+// TestDescriptorError_Text pins the protodesc error text which newDescriptorError relies on
+func TestDescriptorError_Text(t *testing.T) {
+	file := newTestFile()
+	file.GetMessage("Foo").GetField("value").SetTypeName(".foo.Missing")
+	NewPackages().AddFile(file)
+
+	_, err := file.ToFileDescriptor()
+	var e *DescriptorError
+	if assert.True(t, errors.As(err, &e)) {
+		// protobuf-go randomizes the space after the "proto:" prefix
+		assert.True(t, strings.HasSuffix(e.Err.Error(), `message field "foo.Foo.value" cannot resolve type: "foo.Missing" not found`), e.Err.Error())
+		assert.Equal(t, "foo.Foo.value", e.FullName)
+	}
+}
@@ -0,0 +1,347 @@
+package descriptor
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// numberRange is the range of the numbers from start to end inclusive
+type numberRange struct {
+	start int32
+	end   int32
+}
+
+// mergeRanges sorts the ranges and merges the overlapping and adjacent ones
+func mergeRanges(ranges []numberRange) []numberRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	var merged []numberRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && int64(r.start) <= int64(merged[n-1].end)+1 {
+			if r.end > merged[n-1].end {
+				merged[n-1].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// AddReservedRange reserves the field numbers from start to end inclusive, the overlapping and
+// adjacent reserved ranges are merged.
+func (m *Message) AddReservedRange(start int32, end int32) *Message {
+	if m != nil && m.Proto != nil {
+		ranges := []numberRange{{start: start, end: end}}
+		for _, r := range m.Proto.ReservedRange {
+			ranges = append(ranges, numberRange{start: r.GetStart(), end: r.GetEnd() - 1})
+		}
+
+		m.Proto.ReservedRange = nil
+		for _, r := range mergeRanges(ranges) {
+			m.Proto.ReservedRange = append(m.Proto.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+				Start: proto.Int32(r.start),
+				End:   proto.Int32(r.end + 1),
+			})
+		}
+	}
+	return m
+}
+
+func (m *Message) AddReservedNumbers(numbers ...int32) *Message {
+	for _, number := range numbers {
+		m.AddReservedRange(number, number)
+	}
+	return m
+}
+
+func (m *Message) AddReservedNames(names ...string) *Message {
+	if m != nil && m.Proto != nil {
+		for _, name := range names {
+			if !m.IsReservedName(name) {
+				m.Proto.ReservedName = append(m.Proto.ReservedName, name)
+			}
+		}
+	}
+	return m
+}
+
+func (m *Message) GetReservedNames() []string {
+	return m.proto().GetReservedName()
+}
+
+func (m *Message) IsReservedNumber(number int32) bool {
+	for _, r := range m.proto().GetReservedRange() {
+		if number >= r.GetStart() && number < r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Message) IsReservedName(name string) bool {
+	for _, n := range m.proto().GetReservedName() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddExtensionRange declares the field numbers from start to end inclusive for the extensions,
+// the overlapping and adjacent extension ranges are merged unless they have options.
+func (m *Message) AddExtensionRange(start int32, end int32) *Message {
+	if m != nil && m.Proto != nil {
+		ranges := []numberRange{{start: start, end: end}}
+		var extensionRanges []*descriptorpb.DescriptorProto_ExtensionRange
+		for _, r := range m.Proto.ExtensionRange {
+			if r.Options != nil {
+				extensionRanges = append(extensionRanges, r)
+			} else {
+				ranges = append(ranges, numberRange{start: r.GetStart(), end: r.GetEnd() - 1})
+			}
+		}
+
+		for _, r := range mergeRanges(ranges) {
+			extensionRanges = append(extensionRanges, &descriptorpb.DescriptorProto_ExtensionRange{
+				Start: proto.Int32(r.start),
+				End:   proto.Int32(r.end + 1),
+			})
+		}
+		sort.SliceStable(extensionRanges, func(i, j int) bool {
+			return extensionRanges[i].GetStart() < extensionRanges[j].GetStart()
+		})
+		m.Proto.ExtensionRange = extensionRanges
+	}
+	return m
+}
+
+// IsExtensionNumber checks whether the number is in the extension ranges
+func (m *Message) IsExtensionNumber(number int32) bool {
+	for _, r := range m.proto().GetExtensionRange() {
+		if number >= r.GetStart() && number < r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFieldNumberAvailable checks whether the number could be used by a new field, which is a valid
+// field number not used by the fields, reserved or declared for the extensions.
+func (m *Message) IsFieldNumberAvailable(number int32) bool {
+	if m == nil || number < 1 || number > MaxFieldNumber ||
+		number >= reservedFieldNumberStart && number <= reservedFieldNumberEnd {
+		return false
+	}
+	if m.IsReservedNumber(number) || m.IsExtensionNumber(number) {
+		return false
+	}
+	for _, field := range m.Fields {
+		if field.GetNumber() == number {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFieldNameAvailable checks whether the name could be used by a new field, which is not reserved
+// or conflicted with the other elements in the scope of the message.
+func (m *Message) IsFieldNameAvailable(name string) bool {
+	if m == nil || m.IsReservedName(name) {
+		return false
+	}
+	if m.GetField(name) != nil || m.GetOneof(name) != nil || m.GetMessage(name) != nil || m.GetEnum(name) != nil || m.GetExtension(name) != nil {
+		return false
+	}
+	for _, enum := range m.Enums {
+		if enum.GetValue(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// RemoveField removes the field by the name, and the oneof containing it if it's the last field of the oneof,
+// or the nested map entry message if it's a map field
+func (m *Message) RemoveField(name string) *Field {
+	if m == nil || m.Proto == nil {
+		return nil
+	}
+
+	for i, field := range m.Fields {
+		if field.GetName() != name {
+			continue
+		}
+
+		m.Fields = append(m.Fields[:i:i], m.Fields[i+1:]...)
+		m.Proto.Field = append(m.Proto.Field[:i:i], m.Proto.Field[i+1:]...)
+
+		if oneof := field.Oneof; oneof != nil {
+			for j, f := range oneof.Fields {
+				if f == field {
+					oneof.Fields = append(oneof.Fields[:j:j], oneof.Fields[j+1:]...)
+					break
+				}
+			}
+			if len(oneof.Fields) == 0 {
+				m.removeOneof(oneof)
+			}
+			field.Oneof = nil
+		}
+		if entry := field.mapEntry(); entry != nil {
+			m.removeMessage(entry)
+		}
+		return field
+	}
+	return nil
+}
+
+func (m *Message) removeMessage(msg *Message) {
+	for i, nested := range m.Messages {
+		if nested != msg {
+			continue
+		}
+
+		m.Messages = append(m.Messages[:i:i], m.Messages[i+1:]...)
+		m.Proto.NestedType = append(m.Proto.NestedType[:i:i], m.Proto.NestedType[i+1:]...)
+		if packages := m.File.GetPackages(); packages != nil && packages.MessagesByName[msg.GetFullName()] == msg {
+			delete(packages.MessagesByName, msg.GetFullName())
+		}
+		return
+	}
+}
+
+func (m *Message) removeOneof(oneof *Oneof) {
+	for i, o := range m.Oneofs {
+		if o != oneof {
+			continue
+		}
+
+		m.Oneofs = append(m.Oneofs[:i:i], m.Oneofs[i+1:]...)
+		m.Proto.OneofDecl = append(m.Proto.OneofDecl[:i:i], m.Proto.OneofDecl[i+1:]...)
+		for _, field := range m.Fields {
+			if index := field.Proto.OneofIndex; index != nil && *index > int32(i) {
+				field.Proto.OneofIndex = proto.Int32(*index - 1)
+			}
+		}
+		return
+	}
+}
+
+// RetireField removes the field by the name, and reserves its number and name
+func (m *Message) RetireField(name string) *Field {
+	field := m.RemoveField(name)
+	if field != nil {
+		m.AddReservedNumbers(field.GetNumber())
+		m.AddReservedNames(field.GetName())
+	}
+	return field
+}
+
+// AddReservedRange reserves the enum numbers from start to end inclusive, the overlapping and
+// adjacent reserved ranges are merged.
+func (m *Enum) AddReservedRange(start int32, end int32) *Enum {
+	if m != nil && m.Proto != nil {
+		ranges := []numberRange{{start: start, end: end}}
+		for _, r := range m.Proto.ReservedRange {
+			ranges = append(ranges, numberRange{start: r.GetStart(), end: r.GetEnd()})
+		}
+
+		m.Proto.ReservedRange = nil
+		for _, r := range mergeRanges(ranges) {
+			m.Proto.ReservedRange = append(m.Proto.ReservedRange, &descriptorpb.EnumDescriptorProto_EnumReservedRange{
+				Start: proto.Int32(r.start),
+				End:   proto.Int32(r.end),
+			})
+		}
+	}
+	return m
+}
+
+func (m *Enum) AddReservedNumbers(numbers ...int32) *Enum {
+	for _, number := range numbers {
+		m.AddReservedRange(number, number)
+	}
+	return m
+}
+
+func (m *Enum) AddReservedNames(names ...string) *Enum {
+	if m != nil && m.Proto != nil {
+		for _, name := range names {
+			if !m.IsReservedName(name) {
+				m.Proto.ReservedName = append(m.Proto.ReservedName, name)
+			}
+		}
+	}
+	return m
+}
+
+func (m *Enum) GetReservedNames() []string {
+	return m.proto().GetReservedName()
+}
+
+func (m *Enum) IsReservedNumber(number int32) bool {
+	for _, r := range m.proto().GetReservedRange() {
+		if number >= r.GetStart() && number <= r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Enum) IsReservedName(name string) bool {
+	for _, n := range m.proto().GetReservedName() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValueNumberAvailable checks whether the number is neither used by the values nor reserved
+func (m *Enum) IsValueNumberAvailable(number int32) bool {
+	if m == nil || m.IsReservedNumber(number) {
+		return false
+	}
+	for _, value := range m.Values {
+		if value.GetNumber() == number {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValueNameAvailable checks whether the name is neither used by the values nor reserved
+func (m *Enum) IsValueNameAvailable(name string) bool {
+	return m != nil && !m.IsReservedName(name) && m.GetValue(name) == nil
+}
+
+// RemoveValue removes the enum value by the name
+func (m *Enum) RemoveValue(name string) *EnumValue {
+	if m == nil || m.Proto == nil {
+		return nil
+	}
+
+	for i, value := range m.Values {
+		if value.GetName() == name {
+			m.Values = append(m.Values[:i:i], m.Values[i+1:]...)
+			m.Proto.Value = append(m.Proto.Value[:i:i], m.Proto.Value[i+1:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// RetireValue removes the enum value by the name, and reserves its number and name
+func (m *Enum) RetireValue(name string) *EnumValue {
+	value := m.RemoveValue(name)
+	if value != nil {
+		m.AddReservedNumbers(value.GetNumber())
+		m.AddReservedNames(value.GetName())
+	}
+	return value
+}
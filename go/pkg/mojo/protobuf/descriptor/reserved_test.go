@@ -0,0 +1,93 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMessage_AddReservedRange(t *testing.T) {
+	message := NewMessage(NewFile()).SetName("Foo")
+	message.AddReservedRange(5, 8).AddReservedNumbers(2, 3, 9).AddReservedRange(20, 30).AddReservedNames("foo", "bar", "foo")
+
+	assert.Equal(t, []*descriptorpb.DescriptorProto_ReservedRange{
+		{Start: proto.Int32(2), End: proto.Int32(4)},
+		{Start: proto.Int32(5), End: proto.Int32(10)},
+		{Start: proto.Int32(20), End: proto.Int32(31)},
+	}, message.Proto.ReservedRange)
+	assert.Equal(t, []string{"foo", "bar"}, message.GetReservedNames())
+
+	message.AddReservedRange(4, 25)
+	if assert.Len(t, message.Proto.ReservedRange, 1) {
+		assert.Equal(t, int32(2), message.Proto.ReservedRange[0].GetStart())
+		assert.Equal(t, int32(31), message.Proto.ReservedRange[0].GetEnd())
+	}
+	assert.True(t, message.IsReservedNumber(30))
+	assert.False(t, message.IsReservedNumber(31))
+
+	message.AddExtensionRange(100, 199).AddExtensionRange(200, MaxFieldNumber)
+	if assert.Len(t, message.Proto.ExtensionRange, 1) {
+		assert.Equal(t, int32(MaxFieldNumber+1), message.Proto.ExtensionRange[0].GetEnd())
+	}
+	assert.True(t, message.IsExtensionNumber(100))
+	assert.False(t, message.IsFieldNumberAvailable(150))
+	assert.False(t, message.IsFieldNumberAvailable(19500))
+	assert.True(t, message.IsFieldNumberAvailable(1))
+	assert.False(t, message.IsFieldNameAvailable("bar"))
+}
+
+func TestMessage_RetireField(t *testing.T) {
+	file := newTestFile()
+	message := file.GetMessage("Foo")
+	message.AppendOneofWith("kind")
+	field := NewField(message, "kind").SetType("String").SetNumber(10)
+	field.Proto.OneofIndex = proto.Int32(0)
+	message.AppendField(field)
+	message.GetOneof("kind").AppendField(field)
+	assert.False(t, message.IsFieldNameAvailable("kind"))
+
+	assert.Equal(t, field, message.RetireField("kind"))
+	assert.Nil(t, message.GetField("kind"))
+	assert.Empty(t, message.Oneofs)
+	assert.Empty(t, message.Proto.OneofDecl)
+	assert.True(t, message.IsReservedNumber(10))
+	assert.True(t, message.IsReservedName("kind"))
+	assert.False(t, message.IsFieldNumberAvailable(10))
+	assert.Nil(t, message.RemoveField("kind"))
+}
+
+func TestMessage_RetireField_Map(t *testing.T) {
+	file := NewFileFrom(protodesc.ToFileDescriptorProto(structpb.File_google_protobuf_struct_proto))
+	packages := NewPackages().AddFile(file)
+	message := file.GetMessage("Struct")
+	assert.NotNil(t, packages.GetMessage("google.protobuf.Struct.FieldsEntry"))
+
+	assert.NotNil(t, message.RetireField("fields"))
+	assert.Nil(t, message.GetMessage("FieldsEntry"))
+	assert.Empty(t, message.Proto.NestedType)
+	assert.Nil(t, packages.GetMessage("google.protobuf.Struct.FieldsEntry"))
+	assert.True(t, message.IsReservedName("fields"))
+
+	_, err := file.ToFileDescriptor()
+	assert.NoError(t, err)
+}
+
+func TestEnum_RetireValue(t *testing.T) {
+	enum := NewEnum(NewFile()).SetName("Kind")
+	enum.AppendValueWith("KIND_UNSPECIFIED", 0).AppendValueWith("KIND_ONE", 1).AppendValueWith("KIND_TWO", 2)
+
+	assert.NotNil(t, enum.RetireValue("KIND_ONE"))
+	assert.Len(t, enum.Proto.Value, 2)
+	enum.AddReservedRange(2, 5)
+	assert.Equal(t, []*descriptorpb.EnumDescriptorProto_EnumReservedRange{
+		{Start: proto.Int32(1), End: proto.Int32(5)},
+	}, enum.Proto.ReservedRange)
+	assert.False(t, enum.IsValueNumberAvailable(5))
+	assert.True(t, enum.IsValueNumberAvailable(6))
+	assert.False(t, enum.IsValueNameAvailable("KIND_ONE"))
+	assert.True(t, enum.IsValueNameAvailable("KIND_THREE"))
+}
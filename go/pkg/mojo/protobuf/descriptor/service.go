@@ -7,8 +7,8 @@
 package descriptor
 
 import (
+    "google.golang.org/protobuf/reflect/protoreflect"
     "google.golang.org/protobuf/types/descriptorpb"
-    "strings"
 )
 
 // Service describes an service.
@@ -48,7 +48,9 @@ func NewServiceFrom(file *File, proto *descriptorpb.ServiceDescriptorProto) *Ser
         Proto:      proto,
     }
 
-    for _, method := range proto.Method {
+    methods := proto.Method
+    proto.Method = nil
+    for _, method := range methods {
         service.AppendMethod(NewMethodFrom(service, method))
     }
 
@@ -69,7 +71,7 @@ func (s *Service) GetName() string {
 func (s *Service) GetFullName() string {
     if s != nil {
         if len(s.FullName) == 0 {
-            s.FullName = strings.Join([]string{s.GetPackageName(), s.GetName()}, ".")
+            s.FullName = concatFullName(s.GetPackageName(), s.GetName())
         }
         return s.FullName
     }
@@ -96,14 +98,34 @@ func (s *Service) HasMethod() bool {
 }
 
 func (s *Service) GetMethod(name string) *Method {
-    for _, m := range s.Methods {
-        if m.GetName() == name {
-            return m
+    if s != nil {
+        for _, m := range s.Methods {
+            if m.GetName() == name {
+                return m
+            }
         }
     }
     return nil
 }
 
+func (s *Service) GetMethods() []*Method {
+    if s != nil {
+        return s.Methods
+    }
+    return nil
+}
+
+// GetMethodsByKind returns the methods of the kind in the declaration order
+func (s *Service) GetMethodsByKind(kind MethodKind) []*Method {
+    var methods []*Method
+    for _, m := range s.GetMethods() {
+        if m.GetKind() == kind {
+            methods = append(methods, m)
+        }
+    }
+    return methods
+}
+
 func (s *Service) IsMethodExist(name string) bool {
     return s.GetMethod(name) != nil
 }
@@ -115,3 +137,50 @@ func (s *Service) AppendMethod(method *Method) *Service {
     }
     return s
 }
+
+func (s *Service) HasOption(extension protoreflect.ExtensionType) bool {
+    return hasOption(s.proto().GetOptions(), extension)
+}
+
+func (s *Service) GetOption(extension protoreflect.ExtensionType) interface{} {
+    return getOption(s.proto().GetOptions(), extension)
+}
+
+func (s *Service) GetBoolOption(extension protoreflect.ExtensionType) bool {
+    return getBoolOption(s.proto().GetOptions(), extension)
+}
+
+func (s *Service) GetInt64Option(extension protoreflect.ExtensionType) int64 {
+    return getInt64Option(s.proto().GetOptions(), extension)
+}
+
+func (s *Service) GetFloat64Option(extension protoreflect.ExtensionType) float64 {
+    return getFloat64Option(s.proto().GetOptions(), extension)
+}
+
+func (s *Service) GetStringOption(extension protoreflect.ExtensionType) string {
+    return getStringOption(s.proto().GetOptions(), extension)
+}
+
+func (s *Service) SetOption(extension protoreflect.ExtensionType, value interface{}) *Service {
+    if s != nil && s.Proto != nil {
+        setOption(&s.Proto.Options, extension, value)
+    }
+    return s
+}
+
+func (s *Service) SetBoolOption(extension protoreflect.ExtensionType, value bool) *Service {
+    return s.SetOption(extension, value)
+}
+
+func (s *Service) SetStringOption(extension protoreflect.ExtensionType, value string) *Service {
+    return s.SetOption(extension, value)
+}
+
+func (s *Service) SetInt64Option(extension protoreflect.ExtensionType, value int64) *Service {
+    return s.SetOption(extension, value)
+}
+
+func (s *Service) SetFloat64Option(extension protoreflect.ExtensionType, value float64) *Service {
+    return s.SetOption(extension, value)
+}
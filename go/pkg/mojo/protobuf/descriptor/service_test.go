@@ -0,0 +1,30 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_GetMethodsByKind(t *testing.T) {
+	service := NewService(NewFileWithName("foo.proto", "foo")).SetName("FooService")
+	service.AppendMethod(NewMethod(service).SetName("Get"))
+	service.AppendMethod(NewMethod(service).SetName("Upload").SetClientStreaming(true))
+	service.AppendMethod(NewMethod(service).SetName("Watch").SetServerStreaming(true))
+	service.AppendMethod(NewMethod(service).SetName("Chat").SetKind(BidiStreamingMethod))
+
+	assert.Equal(t, UnaryMethod, service.GetMethod("Get").GetKind())
+	assert.Equal(t, ClientStreamingMethod, service.GetMethod("Upload").GetKind())
+	assert.Equal(t, ServerStreamingMethod, service.GetMethod("Watch").GetKind())
+	assert.Equal(t, "bidi_streaming", service.GetMethod("Chat").GetKind().String())
+	assert.True(t, service.GetMethod("Chat").IsClientStreaming())
+
+	methods := service.GetMethodsByKind(ServerStreamingMethod)
+	if assert.Len(t, methods, 1) {
+		assert.Equal(t, "Watch", methods[0].GetName())
+	}
+
+	service.GetMethod("Chat").SetKind(UnaryMethod)
+	assert.Len(t, service.GetMethodsByKind(UnaryMethod), 2)
+	assert.Empty(t, service.GetMethodsByKind(BidiStreamingMethod))
+}
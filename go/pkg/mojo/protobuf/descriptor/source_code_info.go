@@ -0,0 +1,185 @@
+package descriptor
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// the field numbers used in the source path, see descriptor.proto
+const (
+	FilePackageFieldNumber     = 2
+	FileDependencyFieldNumber  = 3
+	FileMessageTypeFieldNumber = 4
+	FileEnumTypeFieldNumber    = 5
+	FileServiceFieldNumber     = 6
+	FileExtensionFieldNumber   = 7
+	FileSyntaxFieldNumber      = 12
+	FileEditionFieldNumber     = 13
+
+	MessageFieldFieldNumber          = 2
+	MessageNestedTypeFieldNumber     = 3
+	MessageEnumTypeFieldNumber       = 4
+	MessageExtensionRangeFieldNumber = 5
+	MessageExtensionFieldNumber      = 6
+	MessageOneofDeclFieldNumber      = 8
+	MessageReservedRangeFieldNumber  = 9
+	MessageReservedNameFieldNumber   = 10
+
+	EnumValueFieldNumber         = 2
+	EnumReservedRangeFieldNumber = 4
+	EnumReservedNameFieldNumber  = 5
+
+	ServiceMethodFieldNumber = 2
+)
+
+func appendPath(path protoreflect.SourcePath, num protoreflect.FieldNumber, idx int) protoreflect.SourcePath {
+	p := append(protoreflect.SourcePath(nil), path...) // make copy
+	return append(p, int32(num), int32(idx))
+}
+
+func pathKey(path []int32) string {
+	var segments []string
+	for _, n := range path {
+		segments = append(segments, strconv.Itoa(int(n)))
+	}
+	return strings.Join(segments, ",")
+}
+
+// walk visits all the descriptors in the file with their parent path
+func (f *File) walk(fn func(d *Descriptor)) {
+	if f == nil {
+		return
+	}
+
+	for i, message := range f.Messages {
+		message.walk(appendPath(nil, FileMessageTypeFieldNumber, i), fn)
+	}
+	for i, enum := range f.Enums {
+		enum.walk(appendPath(nil, FileEnumTypeFieldNumber, i), fn)
+	}
+	for i, service := range f.Services {
+		service.walk(appendPath(nil, FileServiceFieldNumber, i), fn)
+	}
+	for i, extension := range f.Extensions {
+		extension.Path = appendPath(nil, FileExtensionFieldNumber, i)
+		fn(&extension.Descriptor)
+	}
+}
+
+func (m *Message) walk(path protoreflect.SourcePath, fn func(d *Descriptor)) {
+	m.Path = path
+	fn(&m.Descriptor)
+
+	for i, field := range m.Fields {
+		field.Path = appendPath(path, MessageFieldFieldNumber, i)
+		fn(&field.Descriptor)
+	}
+	for i, msg := range m.Messages {
+		msg.walk(appendPath(path, MessageNestedTypeFieldNumber, i), fn)
+	}
+	for i, enum := range m.Enums {
+		enum.walk(appendPath(path, MessageEnumTypeFieldNumber, i), fn)
+	}
+	for i, oneof := range m.Oneofs {
+		oneof.Path = appendPath(path, MessageOneofDeclFieldNumber, i)
+		fn(&oneof.Descriptor)
+	}
+	for i, extension := range m.Extensions {
+		extension.Path = appendPath(path, MessageExtensionFieldNumber, i)
+		fn(&extension.Descriptor)
+	}
+}
+
+func (m *Enum) walk(path protoreflect.SourcePath, fn func(d *Descriptor)) {
+	m.Path = path
+	fn(&m.Descriptor)
+
+	for i, value := range m.Values {
+		value.Path = appendPath(path, EnumValueFieldNumber, i)
+		fn(&value.Descriptor)
+	}
+}
+
+func (s *Service) walk(path protoreflect.SourcePath, fn func(d *Descriptor)) {
+	s.Path = path
+	fn(&s.Descriptor)
+
+	for i, method := range s.Methods {
+		method.Path = appendPath(path, ServiceMethodFieldNumber, i)
+		fn(&method.Descriptor)
+	}
+}
+
+// ExtractComments sets the source path of all the descriptors in the file,
+// and fills their comments from the SourceCodeInfo of the file.
+func (f *File) ExtractComments() {
+	if f == nil || f.Proto == nil {
+		return
+	}
+
+	locations := make(map[string]*descriptorpb.SourceCodeInfo_Location)
+	for _, loc := range f.Proto.GetSourceCodeInfo().GetLocation() {
+		key := pathKey(loc.Path)
+		if _, ok := locations[key]; !ok {
+			locations[key] = loc
+		}
+	}
+
+	f.walk(func(d *Descriptor) {
+		d.Comments = CommentSet{}
+		if loc, ok := locations[pathKey(d.Path)]; ok {
+			d.Comments = makeCommentSet(protoreflect.SourceLocation{
+				LeadingDetachedComments: loc.LeadingDetachedComments,
+				LeadingComments:         loc.GetLeadingComments(),
+				TrailingComments:        loc.GetTrailingComments(),
+			})
+		}
+	})
+}
+
+// UpdateSourceCodeInfo rebuilds the SourceCodeInfo of the file from the current descriptor tree,
+// the paths are computed from the position of each descriptor, and the spans refer to the .proto
+// source text generated by the Printer.
+func (f *File) UpdateSourceCodeInfo() *File {
+	if f == nil || f.Proto == nil {
+		return f
+	}
+
+	f.walk(func(d *Descriptor) {})
+
+	p := &printer{indent: NewPrinter().Indent, spans: make(map[*Descriptor][]int32)}
+	p.file(f)
+
+	info := &descriptorpb.SourceCodeInfo{}
+	info.Location = append(info.Location, &descriptorpb.SourceCodeInfo_Location{
+		Path: []int32{},
+		Span: []int32{0, 0, int32(p.line), 0},
+	})
+	f.walk(func(d *Descriptor) {
+		span := p.spans[d]
+		if span == nil {
+			span = []int32{0, 0, 0}
+		}
+		loc := &descriptorpb.SourceCodeInfo_Location{
+			Path: append([]int32(nil), d.Path...),
+			Span: span,
+		}
+		if len(d.Comments.Leading) > 0 {
+			loc.LeadingComments = proto.String(string(d.Comments.Leading))
+		}
+		if len(d.Comments.Trailing) > 0 {
+			loc.TrailingComments = proto.String(string(d.Comments.Trailing))
+		}
+		for _, detached := range d.Comments.LeadingDetached {
+			loc.LeadingDetachedComments = append(loc.LeadingDetachedComments, string(detached))
+		}
+		info.Location = append(info.Location, loc)
+	})
+
+	f.Proto.SourceCodeInfo = info
+	return f
+}
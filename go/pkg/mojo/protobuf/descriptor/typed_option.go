@@ -0,0 +1,168 @@
+package descriptor
+
+import (
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// OptionHolder is the descriptor wrapper holding the options, which are File, Message, Field,
+// Oneof, Enum, EnumValue, Service and Method.
+type OptionHolder interface {
+	GetOption(extension protoreflect.ExtensionType) interface{}
+}
+
+// OptionTypeError reports the extension option accessed with a mismatched type
+type OptionTypeError struct {
+	Extension protoreflect.FullName
+	Expected  string // the type of the extension value or the extended options
+	Actual    string // the type used to access the option
+}
+
+func (e *OptionTypeError) Error() string {
+	return fmt.Sprintf("option (%s) is %s, not %s", e.Extension, e.Expected, e.Actual)
+}
+
+// LookupTypedOption returns the extension option as T, ok is false if the option is not set.
+//
+// The scalar options are returned as their Go types, the enum options either as the generated
+// enum types or protoreflect.EnumNumber, the message options as the generated message types or
+// proto.Message, and the repeated options as the slices of them.
+func LookupTypedOption[T any](holder OptionHolder, extension protoreflect.ExtensionType) (value T, ok bool, err error) {
+	options := holderOptions(holder, false)
+	if err = checkExtendee(options, extension); err != nil || options == nil {
+		return value, false, err
+	}
+	if !proto.HasExtension(options, extension) {
+		return value, false, nil
+	}
+	value, err = convertTypedOption[T](extension, proto.GetExtension(options, extension))
+	return value, err == nil, err
+}
+
+// GetTypedOption returns the extension option as T, or the default value of the extension if not set
+func GetTypedOption[T any](holder OptionHolder, extension protoreflect.ExtensionType) (T, error) {
+	options := holderOptions(holder, false)
+	if err := checkExtendee(options, extension); err != nil {
+		var zero T
+		return zero, err
+	}
+	if options == nil {
+		return convertTypedOption[T](extension, extension.InterfaceOf(extension.Zero()))
+	}
+	return convertTypedOption[T](extension, proto.GetExtension(options, extension))
+}
+
+// SetTypedOption sets the extension option, the value will be converted to the Go type of the extension
+func SetTypedOption[T any](holder OptionHolder, extension protoreflect.ExtensionType, value T) error {
+	if err := checkExtendee(holderOptions(holder, false), extension); err != nil {
+		return err
+	}
+
+	var v interface{} = value
+	if !extension.IsValidInterface(v) {
+		target := reflect.TypeOf(extension.InterfaceOf(extension.Zero()))
+		converted, ok := convertOptionValue(v, target)
+		if !ok || !extension.IsValidInterface(converted.Interface()) {
+			return &OptionTypeError{Extension: extension.TypeDescriptor().FullName(), Expected: target.String(), Actual: typeString(v)}
+		}
+		v = converted.Interface()
+	}
+
+	if options := holderOptions(holder, true); options != nil {
+		proto.SetExtension(options, extension, v)
+	}
+	return nil
+}
+
+func checkExtendee(options proto.Message, extension protoreflect.ExtensionType) error {
+	if options == nil {
+		return nil
+	}
+	extendee := extension.TypeDescriptor().ContainingMessage().FullName()
+	if name := options.ProtoReflect().Descriptor().FullName(); name != extendee {
+		return &OptionTypeError{Extension: extension.TypeDescriptor().FullName(), Expected: "an option of " + string(extendee), Actual: string(name)}
+	}
+	return nil
+}
+
+func convertTypedOption[T any](extension protoreflect.ExtensionType, value interface{}) (T, error) {
+	var zero T
+	if v, ok := value.(T); ok {
+		return v, nil
+	}
+
+	target := reflect.TypeOf(&zero).Elem()
+	if v, ok := convertOptionValue(value, target); ok {
+		return v.Interface().(T), nil
+	}
+	return zero, &OptionTypeError{Extension: extension.TypeDescriptor().FullName(), Expected: typeString(value), Actual: target.String()}
+}
+
+var (
+	enumNumberType    = reflect.TypeOf(protoreflect.EnumNumber(0))
+	enumInterfaceType = reflect.TypeOf((*protoreflect.Enum)(nil)).Elem()
+)
+
+// convertOptionValue converts between the enum numbers and the enum types, and between the lists
+// and the slices, which are the different representations of the generated and dynamic extensions
+func convertOptionValue(value interface{}, target reflect.Type) (reflect.Value, bool) {
+	if m, ok := value.(protoreflect.Message); ok {
+		value = m.Interface()
+	}
+	if value == nil {
+		return reflect.Value{}, false
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(target) {
+		result := reflect.New(target).Elem()
+		result.Set(v)
+		return result, true
+	}
+
+	switch e := value.(type) {
+	case protoreflect.Enum:
+		if target == enumNumberType {
+			return reflect.ValueOf(e.Number()), true
+		}
+	case protoreflect.EnumNumber:
+		if target.Kind() == reflect.Int32 && target.Implements(enumInterfaceType) {
+			return v.Convert(target), true
+		}
+	case protoreflect.List:
+		if target.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(target, 0, e.Len())
+			for i := 0; i < e.Len(); i++ {
+				element, ok := convertOptionValue(e.Get(i).Interface(), target.Elem())
+				if !ok {
+					return reflect.Value{}, false
+				}
+				slice = reflect.Append(slice, element)
+			}
+			return slice, true
+		}
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && target.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(target, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			element, ok := convertOptionValue(v.Index(i).Interface(), target.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			slice = reflect.Append(slice, element)
+		}
+		return slice, true
+	}
+	return reflect.Value{}, false
+}
+
+func typeString(value interface{}) string {
+	if value == nil {
+		return "nil"
+	}
+	return reflect.TypeOf(value).String()
+}
@@ -0,0 +1,123 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/mojo-lang/core/go/pkg/mojo"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func newTestOptionExtensions(t *testing.T) map[string]protoreflect.ExtensionType {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("foo/options.proto"),
+		Package:    proto.String("foo"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Info"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:   proto.String("name"),
+				Number: proto.Int32(1),
+				Label:  optional,
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Level"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("LEVEL_LOW"), Number: proto.Int32(0)},
+				{Name: proto.String("LEVEL_HIGH"), Number: proto.Int32(1)},
+			},
+		}},
+		Extension: []*descriptorpb.FieldDescriptorProto{{
+			Name:     proto.String("level"),
+			Number:   proto.Int32(50001),
+			Label:    optional,
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+			TypeName: proto.String(".foo.Level"),
+			Extendee: proto.String(".google.protobuf.MessageOptions"),
+		}, {
+			Name:     proto.String("info"),
+			Number:   proto.Int32(50002),
+			Label:    optional,
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+			TypeName: proto.String(".foo.Info"),
+			Extendee: proto.String(".google.protobuf.MessageOptions"),
+		}, {
+			Name:     proto.String("codes"),
+			Number:   proto.Int32(50003),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_UINT32.Enum(),
+			Extendee: proto.String(".google.protobuf.MessageOptions"),
+		}},
+	}, protoregistry.GlobalFiles)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	extensions := make(map[string]protoreflect.ExtensionType)
+	for i := 0; i < fd.Extensions().Len(); i++ {
+		xd := fd.Extensions().Get(i)
+		extensions[string(xd.Name())] = dynamicpb.NewExtensionType(xd)
+	}
+	return extensions
+}
+
+func TestGetTypedOption(t *testing.T) {
+	message := NewMessage(NewFile()).SetName("Foo")
+
+	getters, err := GetTypedOption[bool](message, mojo.E_Getters)
+	assert.NoError(t, err)
+	assert.False(t, getters)
+
+	message.SetBoolOption(mojo.E_Getters, true)
+	getters, err = GetTypedOption[bool](message, mojo.E_Getters)
+	assert.NoError(t, err)
+	assert.True(t, getters)
+
+	_, err = GetTypedOption[string](message, mojo.E_Getters)
+	assert.Error(t, err)
+	_, _, err = LookupTypedOption[string](message, mojo.E_Alias)
+	assert.Error(t, err)
+	assert.Error(t, SetTypedOption(message, mojo.E_Getters, "true"))
+}
+
+func TestSetTypedOption(t *testing.T) {
+	extensions := newTestOptionExtensions(t)
+	message := NewMessage(NewFile()).SetName("Foo")
+
+	_, ok, err := LookupTypedOption[protoreflect.EnumNumber](message, extensions["level"])
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, SetTypedOption(message, extensions["level"], protoreflect.EnumNumber(1)))
+	level, ok, err := LookupTypedOption[protoreflect.EnumNumber](message, extensions["level"])
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, protoreflect.EnumNumber(1), level)
+
+	info := dynamicpb.NewMessage(extensions["info"].TypeDescriptor().Message())
+	info.Set(info.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("foo"))
+	assert.NoError(t, SetTypedOption[proto.Message](message, extensions["info"], info))
+	value, err := GetTypedOption[proto.Message](message, extensions["info"])
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(info, value))
+
+	codes := extensions["codes"]
+	list := codes.New().List()
+	list.Append(protoreflect.ValueOfUint32(1))
+	list.Append(protoreflect.ValueOfUint32(2))
+	message.Proto.Options.ProtoReflect().Set(codes.TypeDescriptor(), protoreflect.ValueOfList(list))
+	values, err := GetTypedOption[[]uint32](message, codes)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{1, 2}, values)
+
+	_, err = GetTypedOption[[]int64](message, codes)
+	assert.Error(t, err)
+}
@@ -0,0 +1,636 @@
+package descriptor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DiagnosticKind classifies the problems found by Validate
+type DiagnosticKind string
+
+const (
+	InvalidSyntax         DiagnosticKind = "invalid_syntax"
+	InvalidName           DiagnosticKind = "invalid_name"
+	DuplicateName         DiagnosticKind = "duplicate_name"
+	DuplicateFieldNumber  DiagnosticKind = "duplicate_field_number"
+	FieldNumberOutOfRange DiagnosticKind = "field_number_out_of_range"
+	ReservedNumber        DiagnosticKind = "reserved_number"
+	ReservedName          DiagnosticKind = "reserved_name"
+	InvalidLabel          DiagnosticKind = "invalid_label"
+	MissingType           DiagnosticKind = "missing_type"
+	UnresolvedType        DiagnosticKind = "unresolved_type"
+	InvalidOneof          DiagnosticKind = "invalid_oneof"
+	InvalidMapEntry       DiagnosticKind = "invalid_map_entry"
+	InvalidExtension      DiagnosticKind = "invalid_extension"
+	EmptyEnum             DiagnosticKind = "empty_enum"
+	FirstEnumValueNotZero DiagnosticKind = "first_enum_value_not_zero"
+	DuplicateEnumNumber   DiagnosticKind = "duplicate_enum_number"
+)
+
+// the max numbers of the fields and the enum values, and the field numbers reserved for the protobuf implementation
+const (
+	MaxFieldNumber = 536870911
+	MaxEnumNumber  = math.MaxInt32
+
+	reservedFieldNumberStart = 19000
+	reservedFieldNumberEnd   = 19999
+)
+
+// Diagnostic is a problem found in the descriptor, which would be rejected by protoc
+type Diagnostic struct {
+	Kind     DiagnosticKind
+	File     string                  // name of the file in which the element is declared
+	FullName string                  // full name of the element
+	Path     protoreflect.SourcePath // source path of the element in the file
+	Message  string
+}
+
+func (d *Diagnostic) Error() string {
+	if len(d.File) > 0 {
+		return fmt.Sprintf("%s: %s: %s", d.File, d.FullName, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.FullName, d.Message)
+}
+
+// Diagnostics is the list of the problems found by Validate
+type Diagnostics []*Diagnostic
+
+func (d Diagnostics) Error() string {
+	var messages []string
+	for _, diagnostic := range d {
+		messages = append(messages, diagnostic.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the Diagnostics as an error, or nil if there are no diagnostics
+func (d Diagnostics) Err() error {
+	if len(d) > 0 {
+		return d
+	}
+	return nil
+}
+
+// Filter returns the diagnostics of the kind
+func (d Diagnostics) Filter(kind DiagnosticKind) Diagnostics {
+	var diagnostics Diagnostics
+	for _, diagnostic := range d {
+		if diagnostic.Kind == kind {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type validator struct {
+	packages    *Packages
+	diagnostics Diagnostics
+}
+
+func newValidator(file *File) *validator {
+	v := &validator{}
+	if file != nil {
+		file.walk(func(d *Descriptor) {})
+		if v.packages = file.Packages; v.packages == nil {
+			v.packages = NewPackages().AddFile(file)
+			file.Packages = nil
+		}
+	}
+	return v
+}
+
+func (v *validator) report(kind DiagnosticKind, d *Descriptor, fullName string, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, &Diagnostic{
+		Kind:     kind,
+		File:     d.File.GetName(),
+		FullName: fullName,
+		Path:     append(protoreflect.SourcePath(nil), d.Path...),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) name(d *Descriptor, fullName string, name string) {
+	if !identifierPattern.MatchString(name) {
+		v.report(InvalidName, d, fullName, "%q is not a valid identifier", name)
+	}
+}
+
+// symbols checks the duplicated names in the same scope
+type symbols map[string]string
+
+func (s symbols) add(v *validator, d *Descriptor, fullName string, name string, kind string) {
+	if previous, ok := s[name]; ok {
+		v.report(DuplicateName, d, fullName, "%q is already defined as %s", name, previous)
+		return
+	}
+	s[name] = kind
+}
+
+// Validate checks the file is legal protobuf, and returns all the problems found
+func (f *File) Validate() Diagnostics {
+	if f == nil || f.Proto == nil {
+		return nil
+	}
+
+	v := newValidator(f)
+	v.file(f)
+	return v.diagnostics
+}
+
+func (v *validator) file(f *File) {
+	d := &Descriptor{File: f, Path: protoreflect.SourcePath{}}
+	switch syntax := f.Proto.GetSyntax(); syntax {
+	case "", Proto2Syntax, Proto3Syntax:
+	case EditionsSyntax:
+		if len(f.Proto.GetEdition()) == 0 {
+			v.report(InvalidSyntax, d, f.GetName(), "the edition is required for the editions syntax")
+		}
+	default:
+		v.report(InvalidSyntax, d, f.GetName(), "unrecognized syntax %q", syntax)
+	}
+
+	if pkg := f.GetPackageName(); len(pkg) > 0 {
+		for _, segment := range strings.Split(pkg, ".") {
+			if !identifierPattern.MatchString(segment) {
+				v.report(InvalidName, d, f.GetName(), "%q is not a valid package name", pkg)
+				break
+			}
+		}
+	}
+
+	scope := make(symbols)
+	for _, message := range f.Messages {
+		scope.add(v, &message.Descriptor, message.GetFullName(), message.GetName(), "message")
+	}
+	for _, enum := range f.Enums {
+		scope.add(v, &enum.Descriptor, enum.GetFullName(), enum.GetName(), "enum")
+		v.enumValueSymbols(scope, enum)
+	}
+	for _, service := range f.Services {
+		scope.add(v, &service.Descriptor, service.GetFullName(), service.GetName(), "service")
+	}
+	for _, extension := range f.Extensions {
+		scope.add(v, &extension.Descriptor, extension.GetFullName(), extension.GetName(), "extension")
+	}
+
+	for _, message := range f.Messages {
+		v.message(message)
+	}
+	for _, enum := range f.Enums {
+		v.enum(enum)
+	}
+	for _, service := range f.Services {
+		v.service(service)
+	}
+	v.extensions(f.Extensions)
+}
+
+// enumValueSymbols adds the enum values into the scope of the enum, as the C++ scoping rules
+func (v *validator) enumValueSymbols(scope symbols, enum *Enum) {
+	for _, value := range enum.Values {
+		scope.add(v, &value.Descriptor, enumValueFullName(enum, value), value.GetName(), "enum value of "+enum.GetName())
+	}
+}
+
+func enumValueFullName(enum *Enum, value *EnumValue) string {
+	if enum.Parent != nil {
+		return concatFullName(enum.Parent.GetFullName(), value.GetName())
+	}
+	return concatFullName(enum.GetPackageName(), value.GetName())
+}
+
+// Validate checks the message and all its nested types
+func (m *Message) Validate() Diagnostics {
+	if m == nil || m.Proto == nil {
+		return nil
+	}
+
+	v := newValidator(m.File)
+	v.message(m)
+	return v.diagnostics
+}
+
+func (v *validator) message(m *Message) {
+	fullName := m.GetFullName()
+	v.name(&m.Descriptor, fullName, m.GetName())
+
+	scope := make(symbols)
+	for _, field := range m.Fields {
+		scope.add(v, &field.Descriptor, field.GetFullName(), field.GetName(), "field")
+	}
+	for _, msg := range m.Messages {
+		scope.add(v, &msg.Descriptor, msg.GetFullName(), msg.GetName(), "message")
+	}
+	for _, enum := range m.Enums {
+		scope.add(v, &enum.Descriptor, enum.GetFullName(), enum.GetName(), "enum")
+		v.enumValueSymbols(scope, enum)
+	}
+	for _, oneof := range m.Oneofs {
+		scope.add(v, &oneof.Descriptor, concatFullName(fullName, oneof.GetName()), oneof.GetName(), "oneof")
+	}
+	for _, extension := range m.Extensions {
+		scope.add(v, &extension.Descriptor, extension.GetFullName(), extension.GetName(), "extension")
+	}
+
+	numbers := make(map[int32]*Field)
+	for _, field := range m.Fields {
+		v.field(field)
+		if previous, ok := numbers[field.GetNumber()]; ok {
+			v.report(DuplicateFieldNumber, &field.Descriptor, field.GetFullName(), "field number %d has already been used by %q", field.GetNumber(), previous.GetName())
+		} else {
+			numbers[field.GetNumber()] = field
+		}
+		v.reservedField(m, field)
+	}
+
+	synthetic := false
+	for _, oneof := range m.Oneofs {
+		v.oneof(m, oneof)
+		if oneof.IsSynthetic() {
+			synthetic = true
+		} else if synthetic {
+			v.report(InvalidOneof, &oneof.Descriptor, concatFullName(m.GetFullName(), oneof.GetName()), "synthetic oneofs must be after all other oneofs")
+		}
+	}
+	if m.IsMapEntry() {
+		v.mapEntry(m)
+	}
+	v.extensions(m.Extensions)
+
+	for _, msg := range m.Messages {
+		v.message(msg)
+	}
+	for _, enum := range m.Enums {
+		v.enum(enum)
+	}
+}
+
+func (v *validator) reservedField(m *Message, field *Field) {
+	number := field.GetNumber()
+	for _, r := range m.Proto.ReservedRange {
+		if number >= r.GetStart() && number < r.GetEnd() {
+			v.report(ReservedNumber, &field.Descriptor, field.GetFullName(), "field number %d is reserved", number)
+		}
+	}
+	for _, r := range m.Proto.ExtensionRange {
+		if number >= r.GetStart() && number < r.GetEnd() {
+			v.report(ReservedNumber, &field.Descriptor, field.GetFullName(), "field number %d is in the extension range %d to %d", number, r.GetStart(), r.GetEnd()-1)
+		}
+	}
+	for _, name := range m.Proto.ReservedName {
+		if name == field.GetName() {
+			v.report(ReservedName, &field.Descriptor, field.GetFullName(), "field name %q is reserved", name)
+		}
+	}
+}
+
+// extensions checks the extensions declared in the same scope
+func (v *validator) extensions(extensions []*Field) {
+	numbers := make(map[string]*Field)
+	for _, extension := range extensions {
+		v.field(extension)
+
+		fullName := extension.GetFullName()
+		if extension.Proto.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
+			v.report(InvalidExtension, &extension.Descriptor, fullName, "the extension can't be required")
+		}
+
+		extendee := extension.Extendee
+		if extendee == nil {
+			extendee, _ = v.packages.Resolve(extension.scope(), extension.GetExtendee())
+		}
+		switch {
+		case len(extension.GetExtendee()) == 0:
+			v.report(InvalidExtension, &extension.Descriptor, fullName, "the extendee of the extension is missing")
+			continue
+		case extendee != nil:
+			if !extendee.IsExtensionNumber(extension.GetNumber()) {
+				v.report(InvalidExtension, &extension.Descriptor, fullName, "%q does not declare %d as an extension number", extendee.GetFullName(), extension.GetNumber())
+			}
+		default:
+			if isMessage, _ := globalType(extension.GetExtendee()); !isMessage {
+				v.report(UnresolvedType, &extension.Descriptor, fullName, "%q is not defined", extension.GetExtendee())
+				continue
+			}
+		}
+
+		key := fmt.Sprintf("%s:%d", strings.TrimPrefix(extension.GetExtendee(), "."), extension.GetNumber())
+		if extendee != nil {
+			key = fmt.Sprintf("%s:%d", extendee.GetFullName(), extension.GetNumber())
+		}
+		if previous, ok := numbers[key]; ok {
+			v.report(DuplicateFieldNumber, &extension.Descriptor, fullName, "extension number %d has already been used by %q", extension.GetNumber(), previous.GetName())
+		} else {
+			numbers[key] = extension
+		}
+	}
+}
+
+func (v *validator) field(field *Field) {
+	fullName := field.GetFullName()
+	v.name(&field.Descriptor, fullName, field.GetName())
+
+	number := field.GetNumber()
+	switch {
+	case number < 1 || number > MaxFieldNumber:
+		v.report(FieldNumberOutOfRange, &field.Descriptor, fullName, "field number %d must be in the range 1 to %d", number, MaxFieldNumber)
+	case number >= reservedFieldNumberStart && number <= reservedFieldNumberEnd:
+		v.report(ReservedNumber, &field.Descriptor, fullName, "field numbers %d through %d are reserved for the protocol buffer library implementation", reservedFieldNumberStart, reservedFieldNumberEnd)
+	}
+
+	label := field.Proto.GetLabel()
+	switch field.File.GetSyntax() {
+	case Proto3Syntax:
+		if label == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
+			v.report(InvalidLabel, &field.Descriptor, fullName, "required fields are not allowed in proto3")
+		}
+		if field.Proto.DefaultValue != nil {
+			v.report(InvalidLabel, &field.Descriptor, fullName, "explicit default values are not allowed in proto3")
+		}
+	case EditionsSyntax:
+		if label == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
+			v.report(InvalidLabel, &field.Descriptor, fullName, "required label is not allowed under editions")
+		}
+	}
+	if label == descriptorpb.FieldDescriptorProto_LABEL_REPEATED && field.Proto.DefaultValue != nil {
+		v.report(InvalidLabel, &field.Descriptor, fullName, "repeated fields can't have default values")
+	}
+
+	v.fieldType(field)
+}
+
+func (v *validator) fieldType(field *Field) {
+	fullName := field.GetFullName()
+	typeName := field.Proto.GetTypeName()
+	switch field.Proto.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+	default:
+		if field.Proto.Type == nil && len(typeName) == 0 {
+			v.report(MissingType, &field.Descriptor, fullName, "the type of the field is missing")
+		}
+		if field.Proto.Type != nil {
+			return
+		}
+	}
+
+	if field.Message != nil || field.Enum != nil {
+		v.mapField(field)
+		return
+	}
+	if len(typeName) == 0 {
+		v.report(MissingType, &field.Descriptor, fullName, "the type name of the field is missing")
+		return
+	}
+
+	message, enum := v.packages.Resolve(field.scope(), typeName)
+	isMessage, isEnum := message != nil, enum != nil
+	if !isMessage && !isEnum {
+		isMessage, isEnum = globalType(typeName)
+	}
+	switch {
+	case !isMessage && !isEnum:
+		v.report(UnresolvedType, &field.Descriptor, fullName, "%q is not defined", typeName)
+	case field.Proto.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM && !isEnum:
+		v.report(UnresolvedType, &field.Descriptor, fullName, "%q is not an enum type", typeName)
+	case field.Proto.Type != nil && field.Proto.GetType() != descriptorpb.FieldDescriptorProto_TYPE_ENUM && !isMessage:
+		v.report(UnresolvedType, &field.Descriptor, fullName, "%q is not a message type", typeName)
+	case message.IsMapEntry():
+		if !field.IsRepeated() {
+			v.report(InvalidMapEntry, &field.Descriptor, fullName, "the map entry %q must be used by a repeated field", typeName)
+		}
+		if message.Parent != field.Parent {
+			v.report(InvalidMapEntry, &field.Descriptor, fullName, "the map entry %q must be nested in the message of the field", typeName)
+		}
+		v.mapEntryName(field, message)
+	}
+}
+
+// globalType looks up the fully-qualified type name in protoregistry.GlobalFiles
+func globalType(typeName string) (isMessage bool, isEnum bool) {
+	if !strings.HasPrefix(typeName, ".") {
+		return false, false
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(typeName[1:]))
+	if err != nil {
+		return false, false
+	}
+	_, isMessage = d.(protoreflect.MessageDescriptor)
+	_, isEnum = d.(protoreflect.EnumDescriptor)
+	return
+}
+
+func (v *validator) mapField(field *Field) {
+	if entry := field.Message; entry.IsMapEntry() {
+		if !field.IsRepeated() {
+			v.report(InvalidMapEntry, &field.Descriptor, field.GetFullName(), "the map entry %q must be used by a repeated field", entry.GetFullName())
+		}
+		if entry.Parent != field.Parent {
+			v.report(InvalidMapEntry, &field.Descriptor, field.GetFullName(), "the map entry %q must be nested in the message of the field", entry.GetFullName())
+		}
+		v.mapEntryName(field, entry)
+	}
+}
+
+func (v *validator) mapEntryName(field *Field, entry *Message) {
+	if name := MapEntryName(field.GetName()); entry.GetName() != name {
+		v.report(InvalidMapEntry, &field.Descriptor, field.GetFullName(), "the map entry of the field must be named %q", name)
+	}
+}
+
+func (v *validator) mapEntry(m *Message) {
+	fullName := m.GetFullName()
+	if !strings.HasSuffix(m.GetName(), "Entry") {
+		v.report(InvalidMapEntry, &m.Descriptor, fullName, "the name of the map entry must end with \"Entry\"")
+	}
+	if len(m.Messages) > 0 || len(m.Enums) > 0 || len(m.Oneofs) > 0 || len(m.Extensions) > 0 || len(m.Proto.ExtensionRange) > 0 {
+		v.report(InvalidMapEntry, &m.Descriptor, fullName, "the map entry must only have the key and value fields")
+	}
+	if len(m.Fields) != 2 || m.Fields[0].GetName() != "key" || m.Fields[0].GetNumber() != 1 ||
+		m.Fields[1].GetName() != "value" || m.Fields[1].GetNumber() != 2 {
+		v.report(InvalidMapEntry, &m.Descriptor, fullName, "the map entry must have the fields \"key = 1\" and \"value = 2\"")
+		return
+	}
+
+	for _, field := range m.Fields {
+		if field.IsRepeated() {
+			v.report(InvalidMapEntry, &field.Descriptor, field.GetFullName(), "the key and value of the map entry must not be repeated")
+		}
+	}
+	switch key := m.Fields[0]; key.Proto.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		v.report(InvalidMapEntry, &key.Descriptor, key.GetFullName(), "the key of the map must be an integral or string type")
+	}
+}
+
+func (v *validator) oneof(m *Message, oneof *Oneof) {
+	fullName := concatFullName(m.GetFullName(), oneof.GetName())
+	v.name(&oneof.Descriptor, fullName, oneof.GetName())
+
+	if len(oneof.Fields) == 0 {
+		v.report(InvalidOneof, &oneof.Descriptor, fullName, "the oneof must have at least one field")
+	}
+	for _, field := range oneof.Fields {
+		if field.IsRepeated() {
+			v.report(InvalidOneof, &field.Descriptor, field.GetFullName(), "fields in oneofs must not be repeated or map")
+		}
+		if field.Proto.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
+			v.report(InvalidOneof, &field.Descriptor, field.GetFullName(), "fields in oneofs must not be required")
+		}
+	}
+}
+
+// Validate checks the enum is legal protobuf
+func (m *Enum) Validate() Diagnostics {
+	if m == nil || m.Proto == nil {
+		return nil
+	}
+
+	v := newValidator(m.File)
+	v.enum(m)
+	return v.diagnostics
+}
+
+func (v *validator) enum(m *Enum) {
+	fullName := m.GetFullName()
+	v.name(&m.Descriptor, fullName, m.GetName())
+
+	if len(m.Values) == 0 {
+		v.report(EmptyEnum, &m.Descriptor, fullName, "enums must contain at least one value")
+		return
+	}
+	if first := m.Values[0]; first.GetNumber() != 0 && m.File.GetSyntax() == Proto3Syntax {
+		v.report(FirstEnumValueNotZero, &first.Descriptor, enumValueFullName(m, first), "the first enum value must be zero in proto3")
+	}
+
+	allowAlias := m.Proto.GetOptions().GetAllowAlias()
+	numbers := make(map[int32]*EnumValue)
+	for _, value := range m.Values {
+		valueFullName := enumValueFullName(m, value)
+		v.name(&value.Descriptor, valueFullName, value.GetName())
+
+		number := value.GetNumber()
+		if previous, ok := numbers[number]; ok && !allowAlias {
+			v.report(DuplicateEnumNumber, &value.Descriptor, valueFullName, "enum value number %d has already been used by %q, set the allow_alias option to allow aliases", number, previous.GetName())
+		} else if !ok {
+			numbers[number] = value
+		}
+
+		for _, r := range m.Proto.ReservedRange {
+			if number >= r.GetStart() && number <= r.GetEnd() {
+				v.report(ReservedNumber, &value.Descriptor, valueFullName, "enum value number %d is reserved", number)
+			}
+		}
+		for _, name := range m.Proto.ReservedName {
+			if name == value.GetName() {
+				v.report(ReservedName, &value.Descriptor, valueFullName, "enum value name %q is reserved", name)
+			}
+		}
+	}
+	if allowAlias && len(numbers) == len(m.Values) {
+		v.report(DuplicateEnumNumber, &m.Descriptor, fullName, "the allow_alias option is set but no values are aliases")
+	}
+}
+
+// Validate checks the service is legal protobuf
+func (s *Service) Validate() Diagnostics {
+	if s == nil || s.Proto == nil {
+		return nil
+	}
+
+	v := newValidator(s.File)
+	v.service(s)
+	return v.diagnostics
+}
+
+func (v *validator) service(s *Service) {
+	fullName := s.GetFullName()
+	v.name(&s.Descriptor, fullName, s.GetName())
+
+	scope := make(symbols)
+	for _, method := range s.Methods {
+		methodFullName := concatFullName(fullName, method.GetName())
+		v.name(&method.Descriptor, methodFullName, method.GetName())
+		scope.add(v, &method.Descriptor, methodFullName, method.GetName(), "method")
+
+		v.methodType(method, methodFullName, method.Input, method.Proto.GetInputType(), "input")
+		v.methodType(method, methodFullName, method.Output, method.Proto.GetOutputType(), "output")
+	}
+}
+
+func (v *validator) methodType(method *Method, fullName string, message *Message, typeName string, kind string) {
+	if message != nil {
+		return
+	}
+	if len(typeName) == 0 {
+		v.report(MissingType, &method.Descriptor, fullName, "the %s type of the method is missing", kind)
+		return
+	}
+	if message, _ = v.packages.Resolve(method.Parent.GetFullName(), typeName); message != nil {
+		return
+	}
+	if isMessage, _ := globalType(typeName); !isMessage {
+		v.report(UnresolvedType, &method.Descriptor, fullName, "the %s type %q is not a defined message", kind, typeName)
+	}
+}
+
+// Validate checks all the files in the Packages, and the full names conflicted across the files
+func (p *Packages) Validate() Diagnostics {
+	if p == nil {
+		return nil
+	}
+
+	var diagnostics Diagnostics
+	symbols := make(map[string]string)
+	add := func(d *Descriptor, fullName string) {
+		if previous, ok := symbols[fullName]; ok && previous != d.File.GetName() {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Kind:     DuplicateName,
+				File:     d.File.GetName(),
+				FullName: fullName,
+				Path:     append(protoreflect.SourcePath(nil), d.Path...),
+				Message:  fmt.Sprintf("%q is already defined in file %q", fullName, previous),
+			})
+		} else if !ok {
+			symbols[fullName] = d.File.GetName()
+		}
+	}
+
+	var addMessage func(message *Message)
+	addMessage = func(message *Message) {
+		add(&message.Descriptor, message.GetFullName())
+		for _, msg := range message.Messages {
+			addMessage(msg)
+		}
+		for _, enum := range message.Enums {
+			add(&enum.Descriptor, enum.GetFullName())
+		}
+	}
+
+	for _, file := range p.sortedFiles() {
+		diagnostics = append(diagnostics, file.Validate()...)
+		for _, message := range file.Messages {
+			addMessage(message)
+		}
+		for _, enum := range file.Enums {
+			add(&enum.Descriptor, enum.GetFullName())
+		}
+		for _, service := range file.Services {
+			add(&service.Descriptor, service.GetFullName())
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].File < diagnostics[j].File
+	})
+	return diagnostics
+}
@@ -0,0 +1,97 @@
+package descriptor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFile_Validate(t *testing.T) {
+	assert.Empty(t, newTestFile().Validate())
+
+	file := newTestFile()
+	message := file.GetMessage("Foo")
+	message.AppendField(NewField(message, "name").SetType("String").SetNumber(1))
+	message.AppendField(NewField(message, "id").SetType("String").SetNumber(19001))
+	message.AppendField(NewField(message, "bar").SetTypeName("Bar").SetNumber(3))
+	message.Fields[4].Proto.Type = &messageType
+
+	diagnostics := file.Validate()
+	if assert.Len(t, diagnostics.Filter(DuplicateName), 1) {
+		assert.Equal(t, "foo.Foo.name", diagnostics.Filter(DuplicateName)[0].FullName)
+	}
+	if assert.Len(t, diagnostics.Filter(DuplicateFieldNumber), 1) {
+		assert.Equal(t, []int32{4, 0, 2, 2}, []int32(diagnostics.Filter(DuplicateFieldNumber)[0].Path))
+	}
+	assert.Len(t, diagnostics.Filter(ReservedNumber), 1)
+	if assert.Len(t, diagnostics.Filter(UnresolvedType), 1) {
+		assert.Equal(t, "foo.Foo.bar", diagnostics.Filter(UnresolvedType)[0].FullName)
+	}
+	assert.Error(t, diagnostics.Err())
+}
+
+func TestEnum_Validate(t *testing.T) {
+	file := NewFileWithName("foo/foo.proto", "foo")
+	enum := NewEnum(file).SetName("Kind")
+	enum.AppendValueWith("KIND_ONE", 1)
+	enum.AppendValueWith("KIND_TWO", 1)
+	file.AppendEnum(enum)
+
+	diagnostics := enum.Validate()
+	assert.Len(t, diagnostics.Filter(FirstEnumValueNotZero), 1)
+	if assert.Len(t, diagnostics.Filter(DuplicateEnumNumber), 1) {
+		assert.Equal(t, "foo.KIND_TWO", diagnostics.Filter(DuplicateEnumNumber)[0].FullName)
+	}
+}
+
+func TestMessage_Validate(t *testing.T) {
+	fd := newTestFileProto()
+	fd.MessageType[0].Field = []*descriptorpb.FieldDescriptorProto{{
+		Name:       proto.String("names"),
+		Number:     proto.Int32(1),
+		Label:      descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		Type:       descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		OneofIndex: proto.Int32(0),
+	}, {
+		Name:     proto.String("values"),
+		Number:   proto.Int32(2),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: proto.String(".foo.Outer.ValuesEntry"),
+	}}
+	fd.MessageType[0].OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("value")}}
+	fd.MessageType[0].NestedType = append(fd.MessageType[0].NestedType, &descriptorpb.DescriptorProto{
+		Name: proto.String("ValuesEntry"),
+		Field: []*descriptorpb.FieldDescriptorProto{{
+			Name:   proto.String("key"),
+			Number: proto.Int32(1),
+			Type:   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum(),
+		}, {
+			Name:   proto.String("value"),
+			Number: proto.Int32(2),
+			Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	})
+
+	diagnostics := NewFileFrom(fd).GetMessage("Outer").Validate()
+	assert.Len(t, diagnostics.Filter(InvalidOneof), 1)
+	assert.Len(t, diagnostics.Filter(InvalidMapEntry), 2)
+	assert.Len(t, diagnostics.Filter(EmptyEnum), 1)
+}
+
+func TestPackages_Validate(t *testing.T) {
+	packages := NewPackages()
+	packages.AddFile(NewFileFrom(newTestFileProto()))
+	fd := newTestFileProto()
+	fd.Name = proto.String("foo/bar.proto")
+	fd.Service = nil
+	packages.AddFile(NewFileFrom(fd))
+
+	diagnostics := packages.Validate().Filter(DuplicateName)
+	if assert.Len(t, diagnostics, 3) {
+		assert.Equal(t, "foo/foo.proto", diagnostics[0].File)
+		assert.Equal(t, "foo.Outer", diagnostics[0].FullName)
+	}
+}
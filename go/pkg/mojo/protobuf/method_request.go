@@ -0,0 +1,266 @@
+package protobuf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/descriptor"
+)
+
+// MethodParameter is a parameter of the method signature
+type MethodParameter struct {
+	Name     string
+	Type     string // the mojo scalar type name, or the full name of the message or enum
+	Repeated bool
+}
+
+// MethodSignature is the signature of the method to generate in the service
+type MethodSignature struct {
+	Name       string
+	Parameters []*MethodParameter
+
+	// Result is the full name of the result message, or the scalar type wrapped in the response
+	// message as the "result" field, the response message will be empty if not set
+	Result         string
+	ResultRepeated bool
+
+	ClientStreaming bool
+	ServerStreaming bool
+
+	// Attributes are the names of the attributes of the method, like MethodRequestTypeAttributeName
+	Attributes []string
+}
+
+func (s *MethodSignature) HasAttribute(name string) bool {
+	for _, attribute := range s.Attributes {
+		if attribute == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestName returns the name of the request message synthesized for the method
+func (s *MethodSignature) RequestName() string {
+	return upperFirst(s.Name) + "Request"
+}
+
+// ResponseName returns the name of the response message synthesized for the method
+func (s *MethodSignature) ResponseName() string {
+	return upperFirst(s.Name) + "Response"
+}
+
+// GenerateMethods appends the methods of the signatures to the service, the input and output of the methods
+// are set to the request and response messages synthesized in the file of the service.
+//
+// When the method has the MethodRequestTypeAttributeName attribute, its only one parameter should be a
+// message, which will be used as the request directly. The result message is used as the response directly too.
+//
+// All the signatures are validated before the service is modified, nothing is generated if any of them is invalid.
+func GenerateMethods(service *descriptor.Service, signatures ...*MethodSignature) error {
+	if service == nil || service.File == nil {
+		return fmt.Errorf("the service should be in a file")
+	}
+
+	var methods []*generatedMethod
+	names := make(map[string]bool)
+	messages := make(map[string]bool)
+	for _, signature := range signatures {
+		if service.IsMethodExist(signature.Name) || names[signature.Name] {
+			return fmt.Errorf("method %s has already been declared in %s", signature.Name, service.GetFullName())
+		}
+		names[signature.Name] = true
+
+		method, err := generateMethod(service.File, signature)
+		if err != nil {
+			return err
+		}
+		for _, message := range method.messages() {
+			if messages[message.GetName()] {
+				return fmt.Errorf("message %s has already been declared in %s", message.GetName(), service.File.GetName())
+			}
+			messages[message.GetName()] = true
+		}
+		methods = append(methods, method)
+	}
+
+	for _, method := range methods {
+		for _, message := range method.messages() {
+			addMessage(service.File, message)
+		}
+		addDependencies(service.File, method.input, method.request)
+		addDependencies(service.File, method.output, method.response)
+
+		service.AppendMethod(descriptor.NewMethod(service).SetName(method.signature.Name).
+			SetInput(method.input).
+			SetOutput(method.output).
+			SetClientStreaming(method.signature.ClientStreaming).
+			SetServerStreaming(method.signature.ServerStreaming))
+	}
+	return nil
+}
+
+// generatedMethod is the validated signature with its request and response messages
+type generatedMethod struct {
+	signature *MethodSignature
+
+	input    *descriptor.Message
+	output   *descriptor.Message
+	request  bool // the input is synthesized
+	response bool // the output is synthesized
+}
+
+func generateMethod(file *descriptor.File, signature *MethodSignature) (method *generatedMethod, err error) {
+	method = &generatedMethod{signature: signature}
+	if method.input, method.request, err = requestMessage(file, signature); err != nil {
+		return nil, err
+	}
+	if method.output, method.response, err = responseMessage(file, signature); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// messages returns the synthesized messages of the method
+func (m *generatedMethod) messages() []*descriptor.Message {
+	var messages []*descriptor.Message
+	if m.request {
+		messages = append(messages, m.input)
+	}
+	if m.response {
+		messages = append(messages, m.output)
+	}
+	return messages
+}
+
+// requestMessage returns the request message of the method, synthesized is true if it's not declared yet
+func requestMessage(file *descriptor.File, signature *MethodSignature) (message *descriptor.Message, synthesized bool, err error) {
+	if signature.HasAttribute(MethodRequestTypeAttributeName) {
+		if len(signature.Parameters) != 1 || signature.Parameters[0].Repeated {
+			return nil, false, fmt.Errorf("method %s with the %s attribute should have only one argument", signature.Name, MethodRequestTypeAttributeName)
+		}
+		if message = lookupMessage(file, signature.Parameters[0].Type); message != nil {
+			return message, false, nil
+		}
+		return nil, false, fmt.Errorf("the argument of method %s should be a message, but got %s", signature.Name, signature.Parameters[0].Type)
+	}
+
+	if message, err = newMessage(file, signature.RequestName()); err != nil {
+		return nil, false, err
+	}
+	for i, parameter := range signature.Parameters {
+		field, err := newField(message, parameter.Name, parameter.Type, parameter.Repeated)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid argument %s of method %s: %w", parameter.Name, signature.Name, err)
+		}
+		message.AppendField(field.SetNumber(int32(i + 1)))
+	}
+	return message, true, nil
+}
+
+// responseMessage returns the response message of the method, synthesized is true if it's not declared yet
+func responseMessage(file *descriptor.File, signature *MethodSignature) (message *descriptor.Message, synthesized bool, err error) {
+	if len(signature.Result) > 0 && !signature.ResultRepeated {
+		if message = lookupMessage(file, signature.Result); message != nil {
+			return message, false, nil
+		}
+	}
+
+	if message, err = newMessage(file, signature.ResponseName()); err != nil {
+		return nil, false, err
+	}
+	if len(signature.Result) > 0 {
+		field, err := newField(message, "result", signature.Result, signature.ResultRepeated)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid result of method %s: %w", signature.Name, err)
+		}
+		message.AppendField(field.SetNumber(1))
+	}
+	return message, true, nil
+}
+
+func newMessage(file *descriptor.File, name string) (*descriptor.Message, error) {
+	if file.IsMessageExist(name) {
+		return nil, fmt.Errorf("message %s has already been declared in %s", name, file.GetName())
+	}
+	return descriptor.NewMessage(file).SetName(name), nil
+}
+
+// addMessage appends the message to the file, and indexes it in the packages of the file
+func addMessage(file *descriptor.File, message *descriptor.Message) {
+	file.AppendMessage(message)
+	if packages := file.GetPackages(); packages != nil {
+		packages.MessagesByName[message.GetFullName()] = message
+	}
+}
+
+// addDependencies imports the file declaring the message, or the files declaring the field types if the
+// message is synthesized
+func addDependencies(file *descriptor.File, message *descriptor.Message, synthesized bool) {
+	if !synthesized {
+		addDependency(file, message.File)
+		return
+	}
+	for _, field := range message.Fields {
+		if field.Message != nil {
+			addDependency(file, field.Message.File)
+		} else if field.Enum != nil {
+			addDependency(file, field.Enum.File)
+		}
+	}
+}
+
+// addDependency appends the import if missing, the existing imports are kept in order for the indices
+// of the public and weak imports
+func addDependency(file *descriptor.File, dependency *descriptor.File) {
+	if dependency == nil || dependency == file {
+		return
+	}
+	for _, name := range file.GetDependencies() {
+		if name == dependency.GetName() {
+			return
+		}
+	}
+	file.AppendDependency(dependency.GetName())
+}
+
+func newField(message *descriptor.Message, name string, typeName string, repeated bool) (*descriptor.Field, error) {
+	var field *descriptor.Field
+	if msg := lookupMessage(message.File, typeName); msg != nil {
+		field = descriptor.NewMessageField(message, name, msg).SetTypeName("." + msg.GetFullName())
+	} else if enum := lookupEnum(message.File, typeName); enum != nil {
+		field = descriptor.NewEnumField(message, name, enum).SetTypeName("." + enum.GetFullName())
+	} else {
+		field = descriptor.NewField(message, name).SetType(typeName)
+		if field.IsMessageType() {
+			return nil, fmt.Errorf("unknown type %s", typeName)
+		}
+	}
+
+	if repeated {
+		field.SetRepeated()
+	}
+	return field, nil
+}
+
+// lookupMessage looks up the message by the full name in the packages, or the name in the file
+func lookupMessage(file *descriptor.File, name string) *descriptor.Message {
+	if message := file.GetPackages().GetMessage(name); message != nil {
+		return message
+	}
+	return file.GetMessage(name)
+}
+
+func lookupEnum(file *descriptor.File, name string) *descriptor.Enum {
+	if enum := file.GetPackages().GetEnum(name); enum != nil {
+		return enum
+	}
+	return file.GetEnum(name)
+}
+
+func upperFirst(name string) string {
+	if len(name) == 0 {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,140 @@
+package protobuf
+
+import (
+	"testing"
+
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/descriptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService() *descriptor.Service {
+	file := descriptor.NewFileWithName("foo/foo.proto", "foo")
+	file.AppendMessage(descriptor.NewMessage(file).SetName("Bar"))
+	descriptor.NewPackages().AddFile(file)
+
+	service := descriptor.NewService(file).SetName("FooService")
+	file.AppendService(service)
+	return service
+}
+
+func TestGenerateMethods(t *testing.T) {
+	service := newTestService()
+	err := GenerateMethods(service, &MethodSignature{
+		Name: "getBar",
+		Parameters: []*MethodParameter{
+			{Name: "id", Type: "String"},
+			{Name: "tags", Type: "Int32", Repeated: true},
+		},
+		Result: "foo.Bar",
+	}, &MethodSignature{
+		Name:       "UpdateBar",
+		Parameters: []*MethodParameter{{Name: "bar", Type: "Bar"}},
+		Result:     "Bool",
+		Attributes: []string{MethodRequestTypeAttributeName},
+	}, &MethodSignature{
+		Name:            "WatchBars",
+		Parameters:      []*MethodParameter{{Name: "bar", Type: "foo.Bar"}},
+		ServerStreaming: true,
+	})
+	if !assert.NoError(t, err) || !assert.Len(t, service.Methods, 3) {
+		return
+	}
+
+	file := service.File
+	get := service.GetMethod("getBar")
+	assert.Equal(t, "foo.GetBarRequest", get.Proto.GetInputType())
+	assert.Equal(t, "foo.Bar", get.Proto.GetOutputType())
+	request := file.GetMessage("GetBarRequest")
+	if assert.NotNil(t, request) && assert.Len(t, request.Fields, 2) {
+		assert.Equal(t, descriptor.FieldTypeString, request.Fields[0].GetTypeName())
+		assert.True(t, request.Fields[1].IsRepeated())
+		assert.Equal(t, int32(2), request.Fields[1].GetNumber())
+	}
+	assert.NotNil(t, file.GetPackages().GetMessage("foo.GetBarRequest"))
+
+	update := service.GetMethod("UpdateBar")
+	assert.Equal(t, "foo.Bar", update.Proto.GetInputType())
+	assert.Equal(t, "foo.UpdateBarResponse", update.Proto.GetOutputType())
+	assert.Equal(t, descriptor.FieldTypeBool, file.GetMessage("UpdateBarResponse").GetField("result").GetTypeName())
+
+	watch := service.GetMethod("WatchBars")
+	assert.Equal(t, descriptor.ServerStreamingMethod, watch.GetKind())
+	assert.Equal(t, ".foo.Bar", file.GetMessage("WatchBarsRequest").GetField("bar").Proto.GetTypeName())
+	assert.Empty(t, file.GetMessage("WatchBarsResponse").Fields)
+	assert.Empty(t, file.Validate())
+}
+
+func TestGenerateMethods_Dependency(t *testing.T) {
+	service := newTestService()
+	service.File.AppendDependency("z.proto")
+	service.File.Proto.PublicDependency = []int32{0}
+	bar := descriptor.NewFileWithName("bar/bar.proto", "bar")
+	bar.AppendMessage(descriptor.NewMessage(bar).SetName("Baz"))
+	bar.AppendEnum(descriptor.NewEnum(bar).SetName("Kind").AppendValueWith("KIND_UNSPECIFIED", 0))
+	service.File.GetPackages().AddFile(bar)
+
+	err := GenerateMethods(service, &MethodSignature{
+		Name:       "GetBaz",
+		Parameters: []*MethodParameter{{Name: "kind", Type: "bar.Kind"}},
+		Result:     "bar.Baz",
+	}, &MethodSignature{
+		Name:       "UpdateBaz",
+		Parameters: []*MethodParameter{{Name: "baz", Type: "bar.Baz"}},
+		Attributes: []string{MethodRequestTypeAttributeName},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"z.proto", "bar/bar.proto"}, service.File.GetDependencies())
+		assert.Contains(t, service.File.PrintToString(), "import public \"z.proto\";\nimport \"bar/bar.proto\";\n")
+	}
+}
+
+func TestGenerateMethods_ExistingDependency(t *testing.T) {
+	service := newTestService()
+	bar := descriptor.NewFileWithName("bar/bar.proto", "bar")
+	bar.AppendMessage(descriptor.NewMessage(bar).SetName("Baz"))
+	service.File.GetPackages().AddFile(bar)
+	service.File.AppendDependency("bar/bar.proto")
+
+	err := GenerateMethods(service, &MethodSignature{Name: "GetBaz", Result: "bar.Baz"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"bar/bar.proto"}, service.File.GetDependencies())
+		_, err = service.File.ToFileDescriptor()
+		assert.NoError(t, err)
+	}
+}
+
+func TestGenerateMethods_Error(t *testing.T) {
+	service := newTestService()
+	err := GenerateMethods(service, &MethodSignature{
+		Name:       "Get",
+		Parameters: []*MethodParameter{{Name: "id", Type: "String"}},
+		Attributes: []string{MethodRequestTypeAttributeName},
+	})
+	assert.Error(t, err)
+
+	err = GenerateMethods(service, &MethodSignature{
+		Name:       "Get",
+		Parameters: []*MethodParameter{{Name: "baz", Type: "Baz"}},
+	})
+	assert.Error(t, err)
+	assert.Empty(t, service.File.Messages[1:])
+	assert.Empty(t, service.Methods)
+	err = GenerateMethods(service, &MethodSignature{
+		Name:       "GetBar",
+		Parameters: []*MethodParameter{{Name: "id", Type: "String"}},
+	}, &MethodSignature{
+		Name:       "getBar",
+		Parameters: []*MethodParameter{{Name: "id", Type: "String"}},
+	})
+	assert.EqualError(t, err, "message GetBarRequest has already been declared in foo/foo.proto")
+
+	err = GenerateMethods(service, &MethodSignature{
+		Name: "GetBar",
+	}, &MethodSignature{
+		Name: "GetBar",
+	})
+	assert.EqualError(t, err, "method GetBar has already been declared in foo.FooService")
+	assert.Empty(t, service.File.Messages[1:])
+	assert.Empty(t, service.Methods)
+	assert.Nil(t, service.File.GetPackages().GetMessage("foo.GetBarRequest"))
+}
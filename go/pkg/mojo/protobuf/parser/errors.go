@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error reports the problem found when parsing the .proto file, the Line and Column are 1-based
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func newError(file string, line int, column int, format string, args ...interface{}) *Error {
+	return &Error{
+		File:    file,
+		Line:    line + 1,
+		Column:  column + 1,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// unquote decodes the quoted string literal with the escapes supported by protoc
+func unquote(text string) (string, error) {
+	if len(text) < 2 {
+		return "", errors.New("invalid string literal")
+	}
+	text = text[1 : len(text)-1]
+
+	b := &strings.Builder{}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(text) {
+			return "", errors.New("invalid escape sequence in string literal")
+		}
+		switch c = text[i]; c {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '\\', '?', '\'', '"':
+			b.WriteByte(c)
+		case 'x', 'X':
+			value, n := 0, 0
+			for ; n < 2 && i+1 < len(text) && isHexDigit(text[i+1]); n++ {
+				i++
+				value = value*16 + hexValue(text[i])
+			}
+			if n == 0 {
+				return "", errors.New("expected hex digits for escape sequence")
+			}
+			b.WriteByte(byte(value))
+		case 'u', 'U':
+			size := 4
+			if c == 'U' {
+				size = 8
+			}
+			if i+size >= len(text) {
+				return "", errors.New("expected hex digits for unicode escape sequence")
+			}
+			value := 0
+			for n := 0; n < size; n++ {
+				i++
+				if !isHexDigit(text[i]) {
+					return "", errors.New("expected hex digits for unicode escape sequence")
+				}
+				value = value*16 + hexValue(text[i])
+			}
+			if value > utf8.MaxRune {
+				return "", errors.New("unicode escape sequence out of range")
+			}
+			b.WriteRune(rune(value))
+		default:
+			if c < '0' || c > '7' {
+				return "", fmt.Errorf("invalid escape sequence \"\\%c\" in string literal", c)
+			}
+			value := int(c - '0')
+			for n := 1; n < 3 && i+1 < len(text) && '0' <= text[i+1] && text[i+1] <= '7'; n++ {
+				i++
+				value = value*8 + int(text[i]-'0')
+			}
+			b.WriteByte(byte(value))
+		}
+	}
+	return b.String(), nil
+}
+
+func hexValue(c byte) int {
+	switch {
+	case isDigit(c):
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	default:
+		return int(c-'A') + 10
+	}
+}
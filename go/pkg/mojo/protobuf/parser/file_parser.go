@@ -0,0 +1,1293 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"double":   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"float":    descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"int64":    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint64":   descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	"int32":    descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"fixed64":  descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	"fixed32":  descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	"bool":     descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"string":   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":    descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	"uint32":   descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	"sfixed32": descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	"sfixed64": descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	"sint32":   descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64":   descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+}
+
+type location struct {
+	path     []int32
+	span     []int32
+	leading  string
+	trailing string
+	detached []string
+}
+
+func (l *location) end(tok *token) {
+	if l != nil {
+		if int(l.span[0]) == tok.line {
+			l.span = []int32{l.span[0], l.span[1], int32(tok.endColumn)}
+		} else {
+			l.span = []int32{l.span[0], l.span[1], int32(tok.line), int32(tok.endColumn)}
+		}
+	}
+}
+
+// typeReference is the type name to be resolved into the fully-qualified name after all files parsed
+type typeReference struct {
+	scope       string
+	tok         *token
+	typeName    **string
+	field       *descriptorpb.FieldDescriptorProto // set the field type if resolved when not nil
+	messageOnly bool                               // the reference must be a message
+}
+
+// maxExtensionRange is the extension range ends with "max", which depends on the message_set_wire_format option
+type maxExtensionRange struct {
+	message        *descriptorpb.DescriptorProto
+	extensionRange *descriptorpb.DescriptorProto_ExtensionRange
+}
+
+// fileParser parses a single .proto file into the FileDescriptorProto,
+// the options and type references are left to resolve until all the imports parsed.
+type fileParser struct {
+	filename string
+	tokens   []*token
+	pos      int
+
+	fd        *descriptorpb.FileDescriptorProto
+	syntax    string
+	locations []*location
+
+	upcomingDoc      string
+	upcomingDetached []string
+
+	imports    []*token // the tokens of the import paths, for reporting the import errors
+	maxRanges  []*maxExtensionRange
+	options    []*pendingOption
+	references []*typeReference
+}
+
+func parseFile(filename string, content string) (*fileParser, error) {
+	tokens, err := tokenize(filename, content)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &fileParser{
+		filename: filename,
+		tokens:   tokens,
+		fd:       &descriptorpb.FileDescriptorProto{Name: proto.String(filename)},
+		syntax:   descriptor.Proto2Syntax,
+	}
+	if err = p.parse(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *fileParser) tok() *token {
+	return p.tokens[p.pos]
+}
+
+func (p *fileParser) next() *token {
+	tok := p.tokens[p.pos]
+	if tok.typ != tokenEnd {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *fileParser) peek() *token {
+	if p.pos+1 < len(p.tokens) {
+		return p.tokens[p.pos+1]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *fileParser) errorf(tok *token, format string, args ...interface{}) *Error {
+	return newError(p.filename, tok.line, tok.column, format, args...)
+}
+
+func (p *fileParser) lookingAt(text string) bool {
+	tok := p.tok()
+	return tok.typ != tokenString && tok.text == text
+}
+
+func (p *fileParser) tryConsume(text string) bool {
+	if p.lookingAt(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *fileParser) consume(text string) error {
+	if !p.tryConsume(text) {
+		return p.errorf(p.tok(), "expected %q, but found %q", text, p.tok().text)
+	}
+	return nil
+}
+
+func (p *fileParser) consumeIdentifier() (string, error) {
+	if tok := p.tok(); tok.typ == tokenIdentifier {
+		p.next()
+		return tok.text, nil
+	}
+	return "", p.errorf(p.tok(), "expected identifier, but found %q", p.tok().text)
+}
+
+// consumeFullIdentifier consumes the dot separated identifiers, with an optional leading dot if allowed
+func (p *fileParser) consumeFullIdentifier(leadingDot bool) (string, error) {
+	b := &strings.Builder{}
+	if leadingDot && p.tryConsume(".") {
+		b.WriteByte('.')
+	}
+	for {
+		id, err := p.consumeIdentifier()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(id)
+		if !p.tryConsume(".") {
+			return b.String(), nil
+		}
+		b.WriteByte('.')
+	}
+}
+
+func (p *fileParser) consumeInteger() (uint64, error) {
+	tok := p.tok()
+	if tok.typ != tokenInteger {
+		return 0, p.errorf(tok, "expected integer, but found %q", tok.text)
+	}
+	p.next()
+	value, err := strconv.ParseUint(tok.text, 0, 64)
+	if err != nil {
+		return 0, p.errorf(tok, "integer out of range: %s", tok.text)
+	}
+	return value, nil
+}
+
+func (p *fileParser) consumeSignedInt32() (int32, error) {
+	tok := p.tok()
+	negative := p.tryConsume("-")
+	value, err := p.consumeInteger()
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		if value > descriptor.MaxEnumNumber+1 {
+			return 0, p.errorf(tok, "integer out of range")
+		}
+		return int32(-int64(value)), nil
+	}
+	if value > descriptor.MaxEnumNumber {
+		return 0, p.errorf(tok, "integer out of range")
+	}
+	return int32(value), nil
+}
+
+func (p *fileParser) consumeFieldNumber() (int32, error) {
+	tok := p.tok()
+	value, err := p.consumeInteger()
+	if err != nil {
+		return 0, err
+	}
+	if value > descriptor.MaxEnumNumber {
+		return 0, p.errorf(tok, "integer out of range")
+	}
+	return int32(value), nil
+}
+
+// consumeString consumes the adjacent string literals as one string
+func (p *fileParser) consumeString() (string, error) {
+	tok := p.tok()
+	if tok.typ != tokenString {
+		return "", p.errorf(tok, "expected string, but found %q", tok.text)
+	}
+	b := &strings.Builder{}
+	for p.tok().typ == tokenString {
+		value, err := unquote(p.next().text)
+		if err != nil {
+			return "", p.errorf(tok, "%s", err.Error())
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+func (p *fileParser) newLocation(path []int32, start *token) *location {
+	loc := &location{
+		path: append([]int32(nil), path...),
+		span: []int32{int32(start.line), int32(start.column), int32(start.endColumn)},
+	}
+	p.locations = append(p.locations, loc)
+	return loc
+}
+
+// consumeEndOfDeclaration consumes the end of the declaration and attaches the comments to the location,
+// the comments are attributed as what the Parser::TryConsumeEndOfDeclaration in protoc does.
+func (p *fileParser) consumeEndOfDeclaration(text string, loc *location) error {
+	tok := p.tok()
+	if err := p.consume(text); err != nil {
+		return err
+	}
+
+	next := p.tok()
+	leading := p.upcomingDoc
+	p.upcomingDoc = next.leading
+	if loc != nil {
+		loc.leading = leading
+		loc.trailing = next.prevTrailing
+		loc.detached = p.upcomingDetached
+		p.upcomingDetached = next.detached
+		if text != "{" {
+			loc.end(tok)
+		}
+	} else if text == "}" {
+		p.upcomingDetached = next.detached
+	} else {
+		p.upcomingDetached = append(p.upcomingDetached, next.detached...)
+	}
+	return nil
+}
+
+func appendPath(path []int32, elements ...int32) []int32 {
+	return append(append([]int32(nil), path...), elements...)
+}
+
+func (p *fileParser) parse() error {
+	first := p.tok()
+	p.upcomingDetached = first.detached
+	p.upcomingDoc = first.leading
+
+	if p.lookingAt("syntax") || p.lookingAt("edition") {
+		if err := p.parseSyntax(); err != nil {
+			return err
+		}
+	}
+
+	for p.tok().typ != tokenEnd {
+		if err := p.parseTopLevelStatement(); err != nil {
+			return err
+		}
+	}
+
+	p.fd.SourceCodeInfo = p.sourceCodeInfo()
+	return nil
+}
+
+func (p *fileParser) sourceCodeInfo() *descriptorpb.SourceCodeInfo {
+	end := p.tok()
+	info := &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{{
+			Path: []int32{},
+			Span: []int32{0, 0, int32(end.line), int32(end.column)},
+		}},
+	}
+	for _, loc := range p.locations {
+		l := &descriptorpb.SourceCodeInfo_Location{
+			Path:                    loc.path,
+			Span:                    loc.span,
+			LeadingDetachedComments: loc.detached,
+		}
+		if len(loc.leading) > 0 {
+			l.LeadingComments = proto.String(loc.leading)
+		}
+		if len(loc.trailing) > 0 {
+			l.TrailingComments = proto.String(loc.trailing)
+		}
+		info.Location = append(info.Location, l)
+	}
+	return info
+}
+
+func (p *fileParser) parseSyntax() error {
+	start := p.tok()
+	isEdition := p.lookingAt("edition")
+	path := []int32{descriptor.FileSyntaxFieldNumber}
+	if isEdition {
+		path = []int32{descriptor.FileEditionFieldNumber}
+	}
+	loc := p.newLocation(path, start)
+
+	p.next()
+	if err := p.consume("="); err != nil {
+		return err
+	}
+	valueTok := p.tok()
+	value, err := p.consumeString()
+	if err != nil {
+		return err
+	}
+
+	if isEdition {
+		p.syntax = descriptor.EditionsSyntax
+		p.fd.Syntax = proto.String(descriptor.EditionsSyntax)
+		p.fd.Edition = proto.String(value)
+	} else {
+		switch value {
+		case descriptor.Proto2Syntax:
+		case descriptor.Proto3Syntax:
+			p.fd.Syntax = proto.String(value)
+		default:
+			return p.errorf(valueTok, "unrecognized syntax identifier %q, this parser only recognizes \"proto2\" and \"proto3\"", value)
+		}
+		p.syntax = value
+	}
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parseTopLevelStatement() error {
+	fd := p.fd
+	pkg := fd.GetPackage()
+	switch {
+	case p.lookingAt(";"):
+		return p.consumeEndOfDeclaration(";", nil)
+	case p.lookingAt("message"):
+		md := &descriptorpb.DescriptorProto{}
+		path := []int32{descriptor.FileMessageTypeFieldNumber, int32(len(fd.MessageType))}
+		fd.MessageType = append(fd.MessageType, md)
+		return p.parseMessage(md, path, pkg)
+	case p.lookingAt("enum"):
+		ed := &descriptorpb.EnumDescriptorProto{}
+		path := []int32{descriptor.FileEnumTypeFieldNumber, int32(len(fd.EnumType))}
+		fd.EnumType = append(fd.EnumType, ed)
+		return p.parseEnum(ed, path, pkg)
+	case p.lookingAt("service"):
+		sd := &descriptorpb.ServiceDescriptorProto{}
+		path := []int32{descriptor.FileServiceFieldNumber, int32(len(fd.Service))}
+		fd.Service = append(fd.Service, sd)
+		return p.parseService(sd, path, pkg)
+	case p.lookingAt("extend"):
+		return p.parseExtend(&fd.Extension, []int32{descriptor.FileExtensionFieldNumber}, nil, pkg)
+	case p.lookingAt("import"):
+		return p.parseImport()
+	case p.lookingAt("package"):
+		return p.parsePackage()
+	case p.lookingAt("option"):
+		return p.parseOptionStatement(func() proto.Message {
+			if fd.Options == nil {
+				fd.Options = &descriptorpb.FileOptions{}
+			}
+			return fd.Options
+		}, pkg)
+	}
+	return p.errorf(p.tok(), "expected top-level statement (e.g. \"message\"), but found %q", p.tok().text)
+}
+
+func (p *fileParser) parseImport() error {
+	start := p.next()
+	fd := p.fd
+	loc := p.newLocation([]int32{descriptor.FileDependencyFieldNumber, int32(len(fd.Dependency))}, start)
+
+	index := int32(len(fd.Dependency))
+	if p.tryConsume("public") {
+		fd.PublicDependency = append(fd.PublicDependency, index)
+	} else if p.tryConsume("weak") {
+		fd.WeakDependency = append(fd.WeakDependency, index)
+	}
+
+	tok := p.tok()
+	name, err := p.consumeString()
+	if err != nil {
+		return err
+	}
+	for _, dep := range fd.Dependency {
+		if dep == name {
+			return p.errorf(tok, "import %q was listed twice", name)
+		}
+	}
+	fd.Dependency = append(fd.Dependency, name)
+	p.imports = append(p.imports, tok)
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parsePackage() error {
+	start := p.tok()
+	if p.fd.Package != nil {
+		return p.errorf(start, "multiple package definitions")
+	}
+	loc := p.newLocation([]int32{descriptor.FilePackageFieldNumber}, start)
+	p.next()
+
+	pkg, err := p.consumeFullIdentifier(false)
+	if err != nil {
+		return err
+	}
+	p.fd.Package = proto.String(pkg)
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parseMessage(md *descriptorpb.DescriptorProto, path []int32, scope string) error {
+	start := p.next()
+	loc := p.newLocation(path, start)
+
+	name, err := p.consumeIdentifier()
+	if err != nil {
+		return err
+	}
+	md.Name = proto.String(name)
+	return p.parseMessageBlock(md, path, concat(scope, name), loc)
+}
+
+func (p *fileParser) parseMessageBlock(md *descriptorpb.DescriptorProto, path []int32, scope string, loc *location) error {
+	if err := p.consumeEndOfDeclaration("{", loc); err != nil {
+		return err
+	}
+
+	for !p.lookingAt("}") {
+		if p.tok().typ == tokenEnd {
+			return p.errorf(p.tok(), "reached end of input in message definition (missing '}')")
+		}
+		if err := p.parseMessageStatement(md, path, scope); err != nil {
+			return err
+		}
+	}
+
+	loc.end(p.tok())
+	if err := p.consumeEndOfDeclaration("}", nil); err != nil {
+		return err
+	}
+
+	p.addSyntheticOneofs(md)
+	return nil
+}
+
+// addSyntheticOneofs adds the oneofs for the proto3 optional fields after all the real oneofs
+func (p *fileParser) addSyntheticOneofs(md *descriptorpb.DescriptorProto) {
+	for _, field := range md.Field {
+		if !field.GetProto3Optional() {
+			continue
+		}
+		name := descriptor.SyntheticOneofName(md, field.GetName())
+		field.OneofIndex = proto.Int32(int32(len(md.OneofDecl)))
+		md.OneofDecl = append(md.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String(name)})
+	}
+}
+
+func (p *fileParser) parseMessageStatement(md *descriptorpb.DescriptorProto, path []int32, scope string) error {
+	switch {
+	case p.lookingAt(";"):
+		return p.consumeEndOfDeclaration(";", nil)
+	case p.lookingAt("message"):
+		nested := &descriptorpb.DescriptorProto{}
+		nestedPath := appendPath(path, descriptor.MessageNestedTypeFieldNumber, int32(len(md.NestedType)))
+		md.NestedType = append(md.NestedType, nested)
+		return p.parseMessage(nested, nestedPath, scope)
+	case p.lookingAt("enum"):
+		ed := &descriptorpb.EnumDescriptorProto{}
+		enumPath := appendPath(path, descriptor.MessageEnumTypeFieldNumber, int32(len(md.EnumType)))
+		md.EnumType = append(md.EnumType, ed)
+		return p.parseEnum(ed, enumPath, scope)
+	case p.lookingAt("extensions"):
+		return p.parseExtensions(md, path, scope)
+	case p.lookingAt("reserved"):
+		return p.parseMessageReserved(md, path)
+	case p.lookingAt("extend"):
+		return p.parseExtend(&md.Extension, appendPath(path, descriptor.MessageExtensionFieldNumber), md, scope)
+	case p.lookingAt("option"):
+		return p.parseOptionStatement(func() proto.Message {
+			if md.Options == nil {
+				md.Options = &descriptorpb.MessageOptions{}
+			}
+			return md.Options
+		}, scope)
+	case p.lookingAt("oneof"):
+		return p.parseOneof(md, path, scope)
+	}
+
+	field := &descriptorpb.FieldDescriptorProto{}
+	fieldPath := appendPath(path, descriptor.MessageFieldFieldNumber, int32(len(md.Field)))
+	md.Field = append(md.Field, field)
+	return p.parseField(field, fieldPath, md, path, scope, false)
+}
+
+// parseField parses the field declaration, including the map and group fields, the map entry and group
+// messages will be added into the container message, which will be the file if it is nil.
+func (p *fileParser) parseField(field *descriptorpb.FieldDescriptorProto, path []int32, container *descriptorpb.DescriptorProto, containerPath []int32, scope string, inOneof bool) error {
+	start := p.tok()
+	loc := p.newLocation(path, start)
+	isExtension := field.Extendee != nil
+
+	if label, ok := labels[start.text]; ok && start.typ == tokenIdentifier && !p.isTypeFollowedByName() {
+		if inOneof {
+			return p.errorf(start, "fields in oneofs must not have labels (required / optional / repeated)")
+		}
+		p.next()
+		switch {
+		case p.syntax == descriptor.Proto3Syntax && label == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED:
+			return p.errorf(start, "required fields are not allowed in proto3")
+		case p.syntax == descriptor.EditionsSyntax && label != descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+			return p.errorf(start, "label %q is not allowed in editions", start.text)
+		case p.syntax == descriptor.Proto3Syntax && label == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL && !isExtension:
+			field.Proto3Optional = proto.Bool(true)
+		}
+		field.Label = label.Enum()
+	} else if !inOneof && p.syntax == descriptor.Proto2Syntax && !p.lookingAt("map") {
+		return p.errorf(start, "expected \"required\", \"optional\", or \"repeated\"")
+	} else {
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	}
+
+	var mapKey, mapValue *descriptorpb.FieldDescriptorProto
+	isGroup := false
+	switch typeTok := p.tok(); {
+	case p.lookingAt("map") && p.peek().text == "<":
+		if start != typeTok {
+			return p.errorf(start, "field labels are not allowed on map fields")
+		}
+		if isExtension {
+			return p.errorf(typeTok, "map fields are not allowed to be extensions")
+		}
+		if inOneof {
+			return p.errorf(typeTok, "map fields are not allowed in oneofs")
+		}
+		p.next()
+		p.next()
+		mapKey = &descriptorpb.FieldDescriptorProto{Name: proto.String("key"), Number: proto.Int32(1)}
+		if err := p.parseType(mapKey, scope); err != nil {
+			return err
+		}
+		if err := p.consume(","); err != nil {
+			return err
+		}
+		mapValue = &descriptorpb.FieldDescriptorProto{Name: proto.String("value"), Number: proto.Int32(2)}
+		if err := p.parseType(mapValue, scope); err != nil {
+			return err
+		}
+		if err := p.consume(">"); err != nil {
+			return err
+		}
+	case p.lookingAt("group") && p.syntax == descriptor.Proto2Syntax:
+		p.next()
+		isGroup = true
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum()
+	default:
+		if err := p.parseType(field, scope); err != nil {
+			return err
+		}
+	}
+
+	nameTok := p.tok()
+	name, err := p.consumeIdentifier()
+	if err != nil {
+		return err
+	}
+	if isGroup {
+		if c := name[0]; c < 'A' || c > 'Z' {
+			return p.errorf(nameTok, "group names must start with a capital letter")
+		}
+		field.TypeName = proto.String(name)
+		name = strings.ToLower(name)
+	}
+	field.Name = proto.String(name)
+
+	if err = p.consume("="); err != nil {
+		return err
+	}
+	number, err := p.consumeFieldNumber()
+	if err != nil {
+		return err
+	}
+	field.Number = proto.Int32(number)
+
+	if err = p.parseFieldOptions(field, scope); err != nil {
+		return err
+	}
+
+	if mapKey != nil {
+		entry := &descriptorpb.DescriptorProto{
+			Name:    proto.String(descriptor.MapEntryName(name)),
+			Field:   []*descriptorpb.FieldDescriptorProto{mapKey, mapValue},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+		for _, f := range entry.Field {
+			f.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+			f.JsonName = proto.String(descriptor.JsonName(f.GetName()))
+		}
+		container.NestedType = append(container.NestedType, entry)
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String(entry.GetName())
+		p.references = append(p.references, &typeReference{scope: scope, tok: start, typeName: &field.TypeName})
+	}
+
+	if isGroup {
+		group := &descriptorpb.DescriptorProto{Name: field.TypeName}
+		var groupPath []int32
+		if container != nil {
+			groupPath = appendPath(containerPath, descriptor.MessageNestedTypeFieldNumber, int32(len(container.NestedType)))
+			container.NestedType = append(container.NestedType, group)
+		} else {
+			groupPath = []int32{descriptor.FileMessageTypeFieldNumber, int32(len(p.fd.MessageType))}
+			p.fd.MessageType = append(p.fd.MessageType, group)
+		}
+		p.references = append(p.references, &typeReference{scope: scope, tok: start, typeName: &field.TypeName})
+		return p.parseMessageBlock(group, groupPath, concat(scope, group.GetName()), loc)
+	}
+
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+var labels = map[string]descriptorpb.FieldDescriptorProto_Label{
+	"optional": descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL,
+	"required": descriptorpb.FieldDescriptorProto_LABEL_REQUIRED,
+	"repeated": descriptorpb.FieldDescriptorProto_LABEL_REPEATED,
+}
+
+// isTypeFollowedByName checks whether the label like token is actually the type name of the field,
+// such as a message named "optional" in editions.
+func (p *fileParser) isTypeFollowedByName() bool {
+	next := p.peek()
+	if next.typ != tokenIdentifier {
+		return false
+	}
+	if p.pos+2 < len(p.tokens) {
+		return p.tokens[p.pos+2].text == "="
+	}
+	return false
+}
+
+func (p *fileParser) parseType(field *descriptorpb.FieldDescriptorProto, scope string) error {
+	tok := p.tok()
+	if typ, ok := scalarTypes[tok.text]; ok && tok.typ == tokenIdentifier {
+		p.next()
+		field.Type = typ.Enum()
+		return nil
+	}
+
+	name, err := p.consumeFullIdentifier(true)
+	if err != nil {
+		return err
+	}
+	field.TypeName = proto.String(name)
+	p.references = append(p.references, &typeReference{scope: scope, tok: tok, typeName: &field.TypeName, field: field})
+	return nil
+}
+
+func (p *fileParser) parseFieldOptions(field *descriptorpb.FieldDescriptorProto, scope string) error {
+	if !p.lookingAt("[") {
+		return nil
+	}
+	return p.parseBracketOptions(func() proto.Message {
+		if field.Options == nil {
+			field.Options = &descriptorpb.FieldOptions{}
+		}
+		return field.Options
+	}, scope, func(name *token, value *optionValue) (bool, error) {
+		switch name.text {
+		case "default":
+			if field.DefaultValue != nil {
+				return true, p.errorf(name, "already set option \"default\"")
+			}
+			if field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+				return true, p.errorf(name, "repeated fields can't have default values")
+			}
+			defaultValue, err := value.defaultValue(field)
+			if err != nil {
+				return true, p.errorf(value.tok, "%s", err.Error())
+			}
+			field.DefaultValue = proto.String(defaultValue)
+			return true, nil
+		case "json_name":
+			if field.JsonName != nil {
+				return true, p.errorf(name, "already set option \"json_name\"")
+			}
+			if value.kind != valueString {
+				return true, p.errorf(value.tok, "expected string for json_name")
+			}
+			field.JsonName = proto.String(value.text)
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+// parseBracketOptions parses the options in brackets, the pseudo options will be handled by the pseudo function if not nil.
+func (p *fileParser) parseBracketOptions(options func() proto.Message, scope string, pseudo func(name *token, value *optionValue) (bool, error)) error {
+	if err := p.consume("["); err != nil {
+		return err
+	}
+	for {
+		nameTok := p.tok()
+		option, err := p.parseOption(options, scope)
+		if err != nil {
+			return err
+		}
+
+		handled := false
+		if pseudo != nil && len(option.name) == 1 && !option.name[0].isExtension {
+			if handled, err = pseudo(nameTok, option.value); err != nil {
+				return err
+			}
+		}
+		if !handled {
+			p.options = append(p.options, option)
+		}
+
+		if !p.tryConsume(",") {
+			break
+		}
+	}
+	return p.consume("]")
+}
+
+func (p *fileParser) parseOptionStatement(options func() proto.Message, scope string) error {
+	start := p.next()
+	loc := &location{span: []int32{int32(start.line), int32(start.column), int32(start.endColumn)}}
+	option, err := p.parseOption(options, scope)
+	if err != nil {
+		return err
+	}
+	p.options = append(p.options, option)
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parseOption(options func() proto.Message, scope string) (*pendingOption, error) {
+	option := &pendingOption{
+		file:    p.filename,
+		tok:     p.tok(),
+		options: options,
+		scope:   scope,
+		syntax:  p.syntax,
+	}
+
+	for {
+		if p.tryConsume("(") {
+			name, err := p.consumeFullIdentifier(true)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.consume(")"); err != nil {
+				return nil, err
+			}
+			option.name = append(option.name, &optionNamePart{name: name, isExtension: true})
+		} else {
+			name, err := p.consumeIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			option.name = append(option.name, &optionNamePart{name: name})
+		}
+		if !p.tryConsume(".") {
+			break
+		}
+	}
+
+	if err := p.consume("="); err != nil {
+		return nil, err
+	}
+	value, err := p.parseOptionValue()
+	if err != nil {
+		return nil, err
+	}
+	option.value = value
+	return option, nil
+}
+
+func (p *fileParser) parseOptionValue() (*optionValue, error) {
+	value := &optionValue{tok: p.tok()}
+	if p.tryConsume("-") {
+		value.negative = true
+	} else {
+		p.tryConsume("+")
+	}
+
+	switch tok := p.tok(); tok.typ {
+	case tokenIdentifier:
+		value.kind = valueIdentifier
+		value.text = p.next().text
+	case tokenInteger:
+		value.kind = valueInteger
+		value.text = p.next().text
+	case tokenFloat:
+		value.kind = valueFloat
+		value.text = p.next().text
+	case tokenString:
+		if value.negative {
+			return nil, p.errorf(tok, "invalid '-' symbol before string")
+		}
+		text, err := p.consumeString()
+		if err != nil {
+			return nil, err
+		}
+		value.kind = valueString
+		value.text = text
+	default:
+		if tok.text != "{" || value.negative {
+			return nil, p.errorf(tok, "expected option value, but found %q", tok.text)
+		}
+		text, err := p.consumeAggregate()
+		if err != nil {
+			return nil, err
+		}
+		value.kind = valueAggregate
+		value.text = text
+	}
+	return value, nil
+}
+
+// consumeAggregate consumes the aggregate value in braces, and returns it as the text format content
+func (p *fileParser) consumeAggregate() (string, error) {
+	start := p.next()
+	var texts []string
+	for depth := 1; ; {
+		tok := p.tok()
+		switch {
+		case tok.typ == tokenEnd:
+			return "", p.errorf(start, "unexpected end of stream while parsing aggregate value")
+		case tok.typ == tokenSymbol && (tok.text == "{" || tok.text == "<"):
+			depth++
+		case tok.typ == tokenSymbol && (tok.text == "}" || tok.text == ">"):
+			depth--
+		}
+		p.next()
+		if depth == 0 {
+			return strings.Join(texts, " "), nil
+		}
+		texts = append(texts, tok.text)
+	}
+}
+
+func (p *fileParser) parseOneof(md *descriptorpb.DescriptorProto, path []int32, scope string) error {
+	start := p.next()
+	index := int32(len(md.OneofDecl))
+	loc := p.newLocation(appendPath(path, descriptor.MessageOneofDeclFieldNumber, index), start)
+
+	name, err := p.consumeIdentifier()
+	if err != nil {
+		return err
+	}
+	oneof := &descriptorpb.OneofDescriptorProto{Name: proto.String(name)}
+	md.OneofDecl = append(md.OneofDecl, oneof)
+
+	if err = p.consumeEndOfDeclaration("{", loc); err != nil {
+		return err
+	}
+
+	for !p.lookingAt("}") {
+		switch {
+		case p.tok().typ == tokenEnd:
+			return p.errorf(p.tok(), "reached end of input in oneof definition (missing '}')")
+		case p.lookingAt(";"):
+			err = p.consumeEndOfDeclaration(";", nil)
+		case p.lookingAt("option"):
+			err = p.parseOptionStatement(func() proto.Message {
+				if oneof.Options == nil {
+					oneof.Options = &descriptorpb.OneofOptions{}
+				}
+				return oneof.Options
+			}, scope)
+		default:
+			field := &descriptorpb.FieldDescriptorProto{OneofIndex: proto.Int32(index)}
+			fieldPath := appendPath(path, descriptor.MessageFieldFieldNumber, int32(len(md.Field)))
+			md.Field = append(md.Field, field)
+			err = p.parseField(field, fieldPath, md, path, scope, true)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(md.Field) == 0 || md.Field[len(md.Field)-1].GetOneofIndex() != index || md.Field[len(md.Field)-1].OneofIndex == nil {
+		return p.errorf(start, "oneof must have at least one field")
+	}
+	loc.end(p.tok())
+	return p.consumeEndOfDeclaration("}", nil)
+}
+
+func (p *fileParser) parseExtend(extensions *[]*descriptorpb.FieldDescriptorProto, path []int32, container *descriptorpb.DescriptorProto, scope string) error {
+	p.next()
+	extendeeTok := p.tok()
+	extendee, err := p.consumeFullIdentifier(true)
+	if err != nil {
+		return err
+	}
+
+	if err = p.consumeEndOfDeclaration("{", &location{}); err != nil {
+		return err
+	}
+
+	containerPath := path[:len(path)-1]
+	first := true
+	for !p.lookingAt("}") {
+		switch {
+		case p.tok().typ == tokenEnd:
+			return p.errorf(p.tok(), "reached end of input in extend definition (missing '}')")
+		case p.lookingAt(";"):
+			err = p.consumeEndOfDeclaration(";", nil)
+		default:
+			field := &descriptorpb.FieldDescriptorProto{Extendee: proto.String(extendee)}
+			fieldPath := appendPath(path, int32(len(*extensions)))
+			*extensions = append(*extensions, field)
+			p.references = append(p.references, &typeReference{scope: scope, tok: extendeeTok, typeName: &field.Extendee, messageOnly: true})
+			err = p.parseField(field, fieldPath, container, containerPath, scope, false)
+			first = false
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if first {
+		return p.errorf(extendeeTok, "expected at least one field in the extend block")
+	}
+	return p.consumeEndOfDeclaration("}", nil)
+}
+
+// parseRanges parses the ranges like "1, 3 to 5, 10 to max", the end of the range returned is inclusive
+func (p *fileParser) parseRanges(max int32, signed bool) ([][2]int32, error) {
+	var ranges [][2]int32
+	for {
+		parse := p.consumeFieldNumber
+		if signed {
+			parse = p.consumeSignedInt32
+		}
+		start, err := parse()
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if p.tryConsume("to") {
+			if p.tryConsume("max") {
+				end = max
+			} else if end, err = parse(); err != nil {
+				return nil, err
+			}
+		}
+		ranges = append(ranges, [2]int32{start, end})
+		if !p.tryConsume(",") {
+			return ranges, nil
+		}
+	}
+}
+
+func (p *fileParser) parseReservedNames() ([]string, error) {
+	var names []string
+	for {
+		var name string
+		var err error
+		if p.syntax == descriptor.EditionsSyntax {
+			name, err = p.consumeIdentifier()
+		} else {
+			name, err = p.consumeString()
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if !p.tryConsume(",") {
+			return names, nil
+		}
+	}
+}
+
+func (p *fileParser) isReservedNames() bool {
+	tok := p.tok()
+	return tok.typ == tokenString || tok.typ == tokenIdentifier && p.syntax == descriptor.EditionsSyntax
+}
+
+func (p *fileParser) parseMessageReserved(md *descriptorpb.DescriptorProto, path []int32) error {
+	start := p.next()
+	if p.isReservedNames() {
+		loc := p.newLocation(appendPath(path, descriptor.MessageReservedNameFieldNumber), start)
+		names, err := p.parseReservedNames()
+		if err != nil {
+			return err
+		}
+		md.ReservedName = append(md.ReservedName, names...)
+		return p.consumeEndOfDeclaration(";", loc)
+	}
+
+	loc := p.newLocation(appendPath(path, descriptor.MessageReservedRangeFieldNumber), start)
+	ranges, err := p.parseRanges(descriptor.MaxFieldNumber, false)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		md.ReservedRange = append(md.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+			Start: proto.Int32(r[0]),
+			End:   proto.Int32(r[1] + 1),
+		})
+	}
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parseExtensions(md *descriptorpb.DescriptorProto, path []int32, scope string) error {
+	start := p.next()
+	loc := p.newLocation(appendPath(path, descriptor.MessageExtensionRangeFieldNumber), start)
+
+	ranges, err := p.parseRanges(-1, false)
+	if err != nil {
+		return err
+	}
+	var added []*descriptorpb.DescriptorProto_ExtensionRange
+	for _, r := range ranges {
+		er := &descriptorpb.DescriptorProto_ExtensionRange{Start: proto.Int32(r[0]), End: proto.Int32(r[1] + 1)}
+		if r[1] == -1 {
+			er.End = proto.Int32(descriptor.MaxFieldNumber + 1)
+			p.maxRanges = append(p.maxRanges, &maxExtensionRange{message: md, extensionRange: er})
+		}
+		added = append(added, er)
+		md.ExtensionRange = append(md.ExtensionRange, er)
+	}
+
+	if p.lookingAt("[") {
+		count := len(p.options)
+		err = p.parseBracketOptions(func() proto.Message {
+			if added[0].Options == nil {
+				added[0].Options = &descriptorpb.ExtensionRangeOptions{}
+			}
+			return added[0].Options
+		}, scope, nil)
+		if err != nil {
+			return err
+		}
+		// the options apply to all the ranges in the declaration
+		for _, option := range p.options[count:] {
+			for _, er := range added[1:] {
+				er := er
+				p.options = append(p.options, option.withOptions(func() proto.Message {
+					if er.Options == nil {
+						er.Options = &descriptorpb.ExtensionRangeOptions{}
+					}
+					return er.Options
+				}))
+			}
+		}
+	}
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parseEnum(ed *descriptorpb.EnumDescriptorProto, path []int32, scope string) error {
+	start := p.next()
+	loc := p.newLocation(path, start)
+
+	name, err := p.consumeIdentifier()
+	if err != nil {
+		return err
+	}
+	ed.Name = proto.String(name)
+
+	if err = p.consumeEndOfDeclaration("{", loc); err != nil {
+		return err
+	}
+
+	for !p.lookingAt("}") {
+		switch {
+		case p.tok().typ == tokenEnd:
+			return p.errorf(p.tok(), "reached end of input in enum definition (missing '}')")
+		case p.lookingAt(";"):
+			err = p.consumeEndOfDeclaration(";", nil)
+		case p.lookingAt("option"):
+			err = p.parseOptionStatement(func() proto.Message {
+				if ed.Options == nil {
+					ed.Options = &descriptorpb.EnumOptions{}
+				}
+				return ed.Options
+			}, scope)
+		case p.lookingAt("reserved"):
+			err = p.parseEnumReserved(ed, path)
+		default:
+			err = p.parseEnumValue(ed, path, scope)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	loc.end(p.tok())
+	return p.consumeEndOfDeclaration("}", nil)
+}
+
+func (p *fileParser) parseEnumValue(ed *descriptorpb.EnumDescriptorProto, path []int32, scope string) error {
+	start := p.tok()
+	loc := p.newLocation(appendPath(path, descriptor.EnumValueFieldNumber, int32(len(ed.Value))), start)
+
+	name, err := p.consumeIdentifier()
+	if err != nil {
+		return err
+	}
+	if err = p.consume("="); err != nil {
+		return err
+	}
+	number, err := p.consumeSignedInt32()
+	if err != nil {
+		return err
+	}
+
+	value := &descriptorpb.EnumValueDescriptorProto{Name: proto.String(name), Number: proto.Int32(number)}
+	ed.Value = append(ed.Value, value)
+
+	if p.lookingAt("[") {
+		err = p.parseBracketOptions(func() proto.Message {
+			if value.Options == nil {
+				value.Options = &descriptorpb.EnumValueOptions{}
+			}
+			return value.Options
+		}, scope, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parseEnumReserved(ed *descriptorpb.EnumDescriptorProto, path []int32) error {
+	start := p.next()
+	if p.isReservedNames() {
+		loc := p.newLocation(appendPath(path, descriptor.EnumReservedNameFieldNumber), start)
+		names, err := p.parseReservedNames()
+		if err != nil {
+			return err
+		}
+		ed.ReservedName = append(ed.ReservedName, names...)
+		return p.consumeEndOfDeclaration(";", loc)
+	}
+
+	loc := p.newLocation(appendPath(path, descriptor.EnumReservedRangeFieldNumber), start)
+	ranges, err := p.parseRanges(descriptor.MaxEnumNumber, true)
+	if err != nil {
+		return err
+	}
+	for _, r := range ranges {
+		ed.ReservedRange = append(ed.ReservedRange, &descriptorpb.EnumDescriptorProto_EnumReservedRange{
+			Start: proto.Int32(r[0]),
+			End:   proto.Int32(r[1]),
+		})
+	}
+	return p.consumeEndOfDeclaration(";", loc)
+}
+
+func (p *fileParser) parseService(sd *descriptorpb.ServiceDescriptorProto, path []int32, scope string) error {
+	start := p.next()
+	loc := p.newLocation(path, start)
+
+	name, err := p.consumeIdentifier()
+	if err != nil {
+		return err
+	}
+	sd.Name = proto.String(name)
+	serviceScope := concat(scope, name)
+
+	if err = p.consumeEndOfDeclaration("{", loc); err != nil {
+		return err
+	}
+
+	for !p.lookingAt("}") {
+		switch {
+		case p.tok().typ == tokenEnd:
+			return p.errorf(p.tok(), "reached end of input in service definition (missing '}')")
+		case p.lookingAt(";"):
+			err = p.consumeEndOfDeclaration(";", nil)
+		case p.lookingAt("option"):
+			err = p.parseOptionStatement(func() proto.Message {
+				if sd.Options == nil {
+					sd.Options = &descriptorpb.ServiceOptions{}
+				}
+				return sd.Options
+			}, serviceScope)
+		case p.lookingAt("rpc"):
+			md := &descriptorpb.MethodDescriptorProto{}
+			methodPath := appendPath(path, descriptor.ServiceMethodFieldNumber, int32(len(sd.Method)))
+			sd.Method = append(sd.Method, md)
+			err = p.parseMethod(md, methodPath, serviceScope)
+		default:
+			err = p.errorf(p.tok(), "expected \"rpc\", but found %q", p.tok().text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	loc.end(p.tok())
+	return p.consumeEndOfDeclaration("}", nil)
+}
+
+func (p *fileParser) parseMethod(md *descriptorpb.MethodDescriptorProto, path []int32, scope string) error {
+	start := p.next()
+	loc := p.newLocation(path, start)
+
+	name, err := p.consumeIdentifier()
+	if err != nil {
+		return err
+	}
+	md.Name = proto.String(name)
+
+	parseType := func(typeName **string, streaming **bool) error {
+		if err := p.consume("("); err != nil {
+			return err
+		}
+		if p.tryConsume("stream") {
+			*streaming = proto.Bool(true)
+		}
+		tok := p.tok()
+		name, err := p.consumeFullIdentifier(true)
+		if err != nil {
+			return err
+		}
+		*typeName = proto.String(name)
+		p.references = append(p.references, &typeReference{scope: scope, tok: tok, typeName: typeName, messageOnly: true})
+		return p.consume(")")
+	}
+
+	if err = parseType(&md.InputType, &md.ClientStreaming); err != nil {
+		return err
+	}
+	if err = p.consume("returns"); err != nil {
+		return err
+	}
+	if err = parseType(&md.OutputType, &md.ServerStreaming); err != nil {
+		return err
+	}
+
+	if !p.lookingAt("{") {
+		return p.consumeEndOfDeclaration(";", loc)
+	}
+
+	if err = p.consumeEndOfDeclaration("{", loc); err != nil {
+		return err
+	}
+	for !p.lookingAt("}") {
+		switch {
+		case p.tok().typ == tokenEnd:
+			return p.errorf(p.tok(), "reached end of input in method options (missing '}')")
+		case p.lookingAt(";"):
+			err = p.consumeEndOfDeclaration(";", nil)
+		case p.lookingAt("option"):
+			err = p.parseOptionStatement(func() proto.Message {
+				if md.Options == nil {
+					md.Options = &descriptorpb.MethodOptions{}
+				}
+				return md.Options
+			}, scope)
+		default:
+			err = p.errorf(p.tok(), "expected \"option\", but found %q", p.tok().text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	loc.end(p.tok())
+	return p.consumeEndOfDeclaration("}", nil)
+}
+
+func concat(scope string, name string) string {
+	if len(scope) > 0 {
+		return scope + "." + name
+	}
+	return name
+}
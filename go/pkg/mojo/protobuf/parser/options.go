@@ -0,0 +1,508 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/descriptor"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type valueKind int
+
+const (
+	valueIdentifier valueKind = iota
+	valueInteger
+	valueFloat
+	valueString
+	valueAggregate
+)
+
+// optionValue is the value of the option before interpreted
+type optionValue struct {
+	tok      *token
+	kind     valueKind
+	negative bool
+	text     string // the identifier, number, unquoted string or the aggregate in text format
+}
+
+func (v *optionValue) signedText() string {
+	if v.negative {
+		return "-" + v.text
+	}
+	return v.text
+}
+
+// defaultValue converts the value into the default_value of the field, which is C-escaped for bytes
+func (v *optionValue) defaultValue(field *descriptorpb.FieldDescriptorProto) (string, error) {
+	switch field.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING:
+		if v.kind != valueString {
+			return "", errors.New("expected string for default value")
+		}
+		return v.text, nil
+	case descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		if v.kind != valueString {
+			return "", errors.New("expected string for default value")
+		}
+		return cEscape(v.text), nil
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return "", errors.New("messages can't have default values")
+	}
+
+	switch v.kind {
+	case valueInteger:
+		value, err := strconv.ParseUint(v.text, 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("integer out of range: %s", v.text)
+		}
+		if v.negative {
+			return "-" + strconv.FormatUint(value, 10), nil
+		}
+		return strconv.FormatUint(value, 10), nil
+	case valueFloat, valueIdentifier:
+		return v.signedText(), nil
+	}
+	return "", errors.New("expected a scalar value for default value")
+}
+
+func cEscape(s string) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if c >= 0x20 && c < 0x7f {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(b, `\%03o`, c)
+			}
+		}
+	}
+	return b.String()
+}
+
+type optionNamePart struct {
+	name        string
+	isExtension bool
+}
+
+func (n *optionNamePart) String() string {
+	if n.isExtension {
+		return "(" + n.name + ")"
+	}
+	return n.name
+}
+
+// pendingOption is the option parsed but not interpreted yet, the interpretation
+// needs all the imported files to resolve the custom options.
+type pendingOption struct {
+	file    string
+	tok     *token
+	options func() proto.Message // returns the options message, creates it if absent
+	scope   string
+	syntax  string
+	name    []*optionNamePart
+	value   *optionValue
+}
+
+func (o *pendingOption) withOptions(options func() proto.Message) *pendingOption {
+	option := *o
+	option.options = options
+	return &option
+}
+
+func (o *pendingOption) fullName() string {
+	var parts []string
+	for _, part := range o.name {
+		parts = append(parts, part.String())
+	}
+	return strings.Join(parts, ".")
+}
+
+func (o *pendingOption) errorf(format string, args ...interface{}) *Error {
+	return newError(o.file, o.tok.line, o.tok.column, format, args...)
+}
+
+// uninterpreted converts the option into the UninterpretedOption
+func (o *pendingOption) uninterpreted() *descriptorpb.UninterpretedOption {
+	option := &descriptorpb.UninterpretedOption{}
+	for _, part := range o.name {
+		option.Name = append(option.Name, &descriptorpb.UninterpretedOption_NamePart{
+			NamePart:    proto.String(part.name),
+			IsExtension: proto.Bool(part.isExtension),
+		})
+	}
+
+	value := o.value
+	switch value.kind {
+	case valueIdentifier:
+		option.IdentifierValue = proto.String(value.signedText())
+	case valueInteger:
+		if v, err := strconv.ParseUint(value.text, 0, 64); err == nil {
+			if value.negative {
+				option.NegativeIntValue = proto.Int64(-int64(v))
+			} else {
+				option.PositiveIntValue = proto.Uint64(v)
+			}
+		}
+	case valueFloat:
+		if v, err := strconv.ParseFloat(value.signedText(), 64); err == nil {
+			option.DoubleValue = proto.Float64(v)
+		}
+	case valueString:
+		option.StringValue = []byte(value.text)
+	case valueAggregate:
+		option.AggregateValue = proto.String(value.text)
+	}
+	return option
+}
+
+// interpreter interprets the pending options into the options messages, the custom options
+// are resolved in protoregistry.GlobalTypes first and then the extensions declared in the parsed files.
+type interpreter struct {
+	packages   *descriptor.Packages
+	files      *protoregistry.Files
+	extensions map[string]*descriptor.File // the full name of the extensions declared in the Packages
+	types      map[string]protoreflect.ExtensionType
+}
+
+func newInterpreter(packages *descriptor.Packages) *interpreter {
+	i := &interpreter{
+		packages:   packages,
+		files:      &protoregistry.Files{},
+		extensions: make(map[string]*descriptor.File),
+		types:      make(map[string]protoreflect.ExtensionType),
+	}
+
+	var addExtensions func(file *descriptor.File, scope string, extensions []*descriptorpb.FieldDescriptorProto, messages []*descriptorpb.DescriptorProto)
+	addExtensions = func(file *descriptor.File, scope string, extensions []*descriptorpb.FieldDescriptorProto, messages []*descriptorpb.DescriptorProto) {
+		for _, extension := range extensions {
+			i.extensions[concat(scope, extension.GetName())] = file
+		}
+		for _, message := range messages {
+			name := concat(scope, message.GetName())
+			addExtensions(file, name, message.Extension, message.NestedType)
+		}
+	}
+	for _, file := range packages.FilesByPath {
+		addExtensions(file, file.GetPackageName(), file.Proto.Extension, file.Proto.MessageType)
+	}
+	return i
+}
+
+func (i *interpreter) interpret(option *pendingOption) error {
+	msg := option.options().ProtoReflect()
+	for idx, part := range option.name {
+		field, err := i.findField(msg.Descriptor(), part, option.scope)
+		if err != nil {
+			if idx == 0 && part.name == "features" && option.syntax == descriptor.EditionsSyntax {
+				// the features are not supported by the descriptor.proto yet, keep them uninterpreted
+				i.addUninterpreted(msg, option)
+				return nil
+			}
+			return option.errorf("option %q: %s", option.fullName(), err.Error())
+		}
+
+		if idx < len(option.name)-1 {
+			if field.Message() == nil || field.IsList() || field.IsMap() {
+				return option.errorf("option %q is an atomic type, not a message", option.fullName())
+			}
+			msg = msg.Mutable(field).Message()
+			continue
+		}
+
+		if err = i.setValue(msg, field, option.value); err != nil {
+			return option.errorf("option %q: %s", option.fullName(), err.Error())
+		}
+	}
+	return nil
+}
+
+func (i *interpreter) addUninterpreted(msg protoreflect.Message, option *pendingOption) {
+	field := msg.Descriptor().Fields().ByName("uninterpreted_option")
+	if field == nil {
+		return
+	}
+	list := msg.Mutable(field).List()
+	list.Append(protoreflect.ValueOfMessage(option.uninterpreted().ProtoReflect()))
+}
+
+func (i *interpreter) findField(message protoreflect.MessageDescriptor, part *optionNamePart, scope string) (protoreflect.FieldDescriptor, error) {
+	if !part.isExtension {
+		if field := message.Fields().ByName(protoreflect.Name(part.name)); field != nil {
+			return field, nil
+		}
+		return nil, fmt.Errorf("%q is not a field of %q", part.name, message.FullName())
+	}
+
+	xt, err := i.findExtension(scope, part.name)
+	if err != nil {
+		return nil, err
+	}
+	field := xt.TypeDescriptor()
+	if field.ContainingMessage().FullName() != message.FullName() {
+		return nil, fmt.Errorf("%q is not an extension of %q", part.name, message.FullName())
+	}
+	return field, nil
+}
+
+// findExtension looks up the extension following the protobuf scoping rules
+func (i *interpreter) findExtension(scope string, name string) (protoreflect.ExtensionType, error) {
+	var candidates []string
+	if strings.HasPrefix(name, ".") {
+		candidates = append(candidates, name[1:])
+	} else {
+		for {
+			candidates = append(candidates, concat(scope, name))
+			if len(scope) == 0 {
+				break
+			}
+			if idx := strings.LastIndex(scope, "."); idx >= 0 {
+				scope = scope[:idx]
+			} else {
+				scope = ""
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if xt, ok := i.types[candidate]; ok {
+			return xt, nil
+		}
+		if xt, err := protoregistry.GlobalTypes.FindExtensionByName(protoreflect.FullName(candidate)); err == nil {
+			i.types[candidate] = xt
+			return xt, nil
+		}
+		if file, ok := i.extensions[candidate]; ok {
+			if _, err := i.packages.RegisterFile(i.files, file); err != nil {
+				return nil, err
+			}
+			d, err := i.files.FindDescriptorByName(protoreflect.FullName(candidate))
+			if err != nil {
+				return nil, err
+			}
+			xt := dynamicpb.NewExtensionType(d.(protoreflect.ExtensionDescriptor))
+			i.types[candidate] = xt
+			return xt, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown extension %q", name)
+}
+
+func (i *interpreter) setValue(msg protoreflect.Message, field protoreflect.FieldDescriptor, value *optionValue) error {
+	if field.IsMap() {
+		return errors.New("map options are not supported")
+	}
+	if !field.IsList() && msg.Has(field) {
+		return errors.New("option was already set")
+	}
+
+	var v protoreflect.Value
+	if field.Message() != nil {
+		if value.kind != valueAggregate {
+			return fmt.Errorf("expected an aggregate value for the message %q", field.Message().FullName())
+		}
+
+		var target protoreflect.Message
+		if field.IsList() {
+			v = msg.Mutable(field).List().NewElement()
+			target = v.Message()
+		} else {
+			target = msg.Mutable(field).Message()
+		}
+
+		// unmarshal into a new message and merge it, as prototext resets the message first
+		parsed := target.New().Interface()
+		if err := (prototext.UnmarshalOptions{Resolver: i}).Unmarshal([]byte(value.text), parsed); err != nil {
+			return fmt.Errorf("error while parsing the aggregate value: %s", err.Error())
+		}
+		proto.Merge(target.Interface(), parsed)
+		if !field.IsList() {
+			return nil
+		}
+	} else {
+		var err error
+		if v, err = scalarValue(field, value); err != nil {
+			return err
+		}
+	}
+
+	if field.IsList() {
+		msg.Mutable(field).List().Append(v)
+	} else {
+		msg.Set(field, v)
+	}
+	return nil
+}
+
+func scalarValue(field protoreflect.FieldDescriptor, value *optionValue) (protoreflect.Value, error) {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		if value.kind != valueIdentifier || value.negative {
+			return protoreflect.Value{}, fmt.Errorf("expected an identifier for the enum %q", field.Enum().FullName())
+		}
+		v := field.Enum().Values().ByName(protoreflect.Name(value.text))
+		if v == nil {
+			return protoreflect.Value{}, fmt.Errorf("enum %q has no value named %q", field.Enum().FullName(), value.text)
+		}
+		return protoreflect.ValueOfEnum(v.Number()), nil
+	case protoreflect.BoolKind:
+		if value.kind == valueIdentifier && !value.negative {
+			switch value.text {
+			case "true":
+				return protoreflect.ValueOfBool(true), nil
+			case "false":
+				return protoreflect.ValueOfBool(false), nil
+			}
+		}
+		return protoreflect.Value{}, errors.New("expected \"true\" or \"false\"")
+	case protoreflect.StringKind:
+		if value.kind != valueString {
+			return protoreflect.Value{}, errors.New("expected a string")
+		}
+		return protoreflect.ValueOfString(value.text), nil
+	case protoreflect.BytesKind:
+		if value.kind != valueString {
+			return protoreflect.Value{}, errors.New("expected a string")
+		}
+		return protoreflect.ValueOfBytes([]byte(value.text)), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, err := floatValue(value)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if field.Kind() == protoreflect.FloatKind {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+	}
+
+	if value.kind != valueInteger {
+		return protoreflect.Value{}, errors.New("expected an integer")
+	}
+	u, err := strconv.ParseUint(value.text, 0, 64)
+	if err != nil {
+		return protoreflect.Value{}, fmt.Errorf("integer out of range: %s", value.text)
+	}
+
+	switch field.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if value.negative && u <= -math.MinInt32 {
+			return protoreflect.ValueOfInt32(int32(-int64(u))), nil
+		} else if !value.negative && u <= math.MaxInt32 {
+			return protoreflect.ValueOfInt32(int32(u)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if value.negative && u <= 1<<63 {
+			return protoreflect.ValueOfInt64(int64(-u)), nil
+		} else if !value.negative && u <= math.MaxInt64 {
+			return protoreflect.ValueOfInt64(int64(u)), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if !value.negative && u <= math.MaxUint32 {
+			return protoreflect.ValueOfUint32(uint32(u)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if !value.negative {
+			return protoreflect.ValueOfUint64(u), nil
+		}
+	}
+	return protoreflect.Value{}, fmt.Errorf("value %s out of range for %s", value.signedText(), field.Kind())
+}
+
+func floatValue(value *optionValue) (float64, error) {
+	switch value.kind {
+	case valueInteger:
+		u, err := strconv.ParseUint(value.text, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("integer out of range: %s", value.text)
+		}
+		if value.negative {
+			return -float64(u), nil
+		}
+		return float64(u), nil
+	case valueFloat:
+		return strconv.ParseFloat(value.signedText(), 64)
+	case valueIdentifier:
+		switch strings.ToLower(value.text) {
+		case "inf", "infinity":
+			if value.negative {
+				return math.Inf(-1), nil
+			}
+			return math.Inf(1), nil
+		case "nan":
+			return math.NaN(), nil
+		}
+	}
+	return 0, errors.New("expected a number")
+}
+
+// FindExtensionByName implements the prototext resolver for the aggregate values
+func (i *interpreter) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	xt, err := i.findExtension("", "."+string(field))
+	if err != nil {
+		return nil, protoregistry.NotFound
+	}
+	return xt, nil
+}
+
+func (i *interpreter) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if xt, err := protoregistry.GlobalTypes.FindExtensionByNumber(message, field); err == nil {
+		return xt, nil
+	}
+	for _, file := range i.packages.FilesByPath {
+		for _, extension := range file.Proto.Extension {
+			if extension.GetNumber() == int32(field) && strings.TrimPrefix(extension.GetExtendee(), ".") == string(message) {
+				return i.FindExtensionByName(protoreflect.FullName(concat(file.GetPackageName(), extension.GetName())))
+			}
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (i *interpreter) FindMessageByName(message protoreflect.FullName) (protoreflect.MessageType, error) {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(message); err == nil {
+		return mt, nil
+	}
+	if msg := i.packages.GetMessage(string(message)); msg != nil {
+		if _, err := i.packages.RegisterFile(i.files, msg.File); err != nil {
+			return nil, err
+		}
+		d, err := i.files.FindDescriptorByName(message)
+		if err != nil {
+			return nil, err
+		}
+		if md, ok := d.(protoreflect.MessageDescriptor); ok {
+			return dynamicpb.NewMessageType(md), nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (i *interpreter) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	name := url
+	if idx := strings.LastIndexByte(url, '/'); idx >= 0 {
+		name = url[idx+1:]
+	}
+	return i.FindMessageByName(protoreflect.FullName(name))
+}
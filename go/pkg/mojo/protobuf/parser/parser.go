@@ -0,0 +1,213 @@
+// Package parser parses the .proto source files into the descriptor files, the same as what protoc does,
+// including the source code info with the comments and the interpreted options.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type Parser struct {
+	// Resolver opens the files to parse and their imports
+	Resolver FileResolver
+
+	// Registry provides the compiled files for the imports not found by the Resolver,
+	// protoregistry.GlobalFiles will be used if nil.
+	Registry *protoregistry.Files
+}
+
+func New(resolver FileResolver) *Parser {
+	return &Parser{Resolver: resolver}
+}
+
+// ParseFile parses the content of the .proto file, the imports are only looked up in protoregistry.GlobalFiles.
+func ParseFile(name string, content []byte) (*descriptor.File, error) {
+	return New(nil).Parse(name, content)
+}
+
+// Parse parses the content as the file named name, the imports are resolved by the Parser.
+func (p *Parser) Parse(name string, content []byte) (*descriptor.File, error) {
+	parser := &Parser{
+		Resolver: &overlayResolver{files: map[string][]byte{name: content}, resolver: p.Resolver},
+		Registry: p.Registry,
+	}
+	packages, err := parser.ParseFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	return packages.FilesByPath[name], nil
+}
+
+// ParseFiles parses the files and all their imports into the Packages, the type names in the files
+// will be fully-qualified and the options interpreted.
+func (p *Parser) ParseFiles(names ...string) (*descriptor.Packages, error) {
+	l := &loader{
+		parser:  p,
+		files:   make(map[string]*loadedFile),
+		loading: make(map[string]bool),
+	}
+	for _, name := range names {
+		if err := l.load(name, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	packages := descriptor.NewPackages()
+	for _, f := range l.order {
+		packages.AddFile(descriptor.NewFileFrom(f.proto))
+	}
+
+	for _, f := range l.order {
+		if f.parsed != nil {
+			if err := f.parsed.resolveReferences(packages); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := packages.Link(); err != nil {
+		return nil, err
+	}
+
+	interpreter := newInterpreter(packages)
+	for _, f := range l.order {
+		if f.parsed != nil {
+			for _, option := range f.parsed.options {
+				if err := interpreter.interpret(option); err != nil {
+					return nil, err
+				}
+			}
+			for _, r := range f.parsed.maxRanges {
+				if r.message.GetOptions().GetMessageSetWireFormat() {
+					r.extensionRange.End = proto.Int32(math.MaxInt32)
+				}
+			}
+		}
+	}
+	return packages, nil
+}
+
+type loadedFile struct {
+	proto  *descriptorpb.FileDescriptorProto
+	parsed *fileParser // nil if the file comes from the registry
+}
+
+// loader loads the files with their imports, the imports are placed before the files importing them
+type loader struct {
+	parser  *Parser
+	files   map[string]*loadedFile
+	order   []*loadedFile
+	loading map[string]bool
+}
+
+func (l *loader) load(name string, importer *fileParser, tok *token) error {
+	if _, ok := l.files[name]; ok {
+		return nil
+	}
+	if l.loading[name] {
+		return importer.errorf(tok, "file recursively imports itself: %q", name)
+	}
+	l.loading[name] = true
+	defer delete(l.loading, name)
+
+	f, err := l.open(name)
+	if err != nil {
+		if importer != nil {
+			return importer.errorf(tok, "import %q was not found or had errors: %s", name, err.Error())
+		}
+		return err
+	}
+
+	for i, dep := range f.proto.Dependency {
+		var depTok *token
+		if f.parsed != nil {
+			depTok = f.parsed.imports[i]
+		}
+		if err = l.load(dep, f.parsed, depTok); err != nil {
+			return err
+		}
+	}
+
+	l.files[name] = f
+	l.order = append(l.order, f)
+	return nil
+}
+
+func (l *loader) open(name string) (*loadedFile, error) {
+	if l.parser.Resolver != nil {
+		reader, err := l.parser.Resolver.Open(name)
+		if err == nil {
+			defer reader.Close()
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, err
+			}
+			parsed, err := parseFile(name, string(content))
+			if err != nil {
+				return nil, err
+			}
+			return &loadedFile{proto: parsed.fd, parsed: parsed}, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	registry := l.parser.Registry
+	if registry == nil {
+		registry = protoregistry.GlobalFiles
+	}
+	fd, err := registry.FindFileByPath(name)
+	if err != nil {
+		return nil, fmt.Errorf("file %q not found", name)
+	}
+	return &loadedFile{proto: protodesc.ToFileDescriptorProto(fd)}, nil
+}
+
+// resolveReferences rewrites the type names into the fully-qualified names, and sets the
+// types of the fields referencing the messages and enums.
+func (p *fileParser) resolveReferences(packages *descriptor.Packages) error {
+	for _, ref := range p.references {
+		typeName := **ref.typeName
+		message, enum := packages.Resolve(ref.scope, typeName)
+		switch {
+		case message != nil:
+			*ref.typeName = proto.String("." + message.GetFullName())
+			if ref.field != nil && ref.field.Type == nil {
+				ref.field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			}
+		case enum != nil && !ref.messageOnly:
+			*ref.typeName = proto.String("." + enum.GetFullName())
+			if ref.field != nil && ref.field.Type == nil {
+				ref.field.Type = descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+			}
+		case enum != nil:
+			return p.errorf(ref.tok, "%q is not a message type", typeName)
+		default:
+			return p.errorf(ref.tok, "%q is not defined", typeName)
+		}
+	}
+
+	var populate func(fields []*descriptorpb.FieldDescriptorProto, messages []*descriptorpb.DescriptorProto)
+	populate = func(fields []*descriptorpb.FieldDescriptorProto, messages []*descriptorpb.DescriptorProto) {
+		for _, field := range fields {
+			if field.JsonName == nil {
+				field.JsonName = proto.String(descriptor.JsonName(field.GetName()))
+			}
+		}
+		for _, message := range messages {
+			populate(message.Field, message.NestedType)
+			populate(message.Extension, nil)
+		}
+	}
+	populate(p.fd.Extension, p.fd.MessageType)
+	return nil
+}
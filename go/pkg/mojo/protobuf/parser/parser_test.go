@@ -0,0 +1,344 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mojo-lang/core/go/pkg/mojo"
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/descriptor"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testProto = `// detached
+
+// leading comment
+syntax = "proto3";
+
+package mojo.test;
+
+import "mojo/mojo.proto";
+
+option go_package = "github.com/mojo-lang/test";
+
+// Foo is a test message
+message Foo {
+  string name = 1 [(mojo.alias) = "n", deprecated = true]; // the name
+  map<string, Bar> bars = 2;
+  optional int64 count = 3;
+  oneof value {
+    int32 number = 4;
+    Kind kind = 5;
+  }
+
+  reserved 10 to 12, 20 to max;
+  reserved "old";
+
+  message Bar {
+    repeated double values = 1 [packed = false];
+  }
+}
+
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_ONE = 1 [deprecated = true];
+  reserved 5 to 10;
+}
+
+service FooService {
+  rpc Get(Foo) returns (Foo.Bar);
+  rpc Watch(stream Foo) returns (stream Foo.Bar) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}
+`
+
+func TestParseFile(t *testing.T) {
+	file, err := ParseFile("mojo/test/foo.proto", []byte(testProto))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, file) {
+		return
+	}
+
+	fd := file.Proto
+	assert.Equal(t, "proto3", fd.GetSyntax())
+	assert.Equal(t, "mojo.test", fd.GetPackage())
+	assert.Equal(t, []string{"mojo/mojo.proto"}, fd.Dependency)
+	assert.Equal(t, "github.com/mojo-lang/test", fd.GetOptions().GetGoPackage())
+
+	foo := file.Messages[0]
+	assert.Equal(t, "mojo.test.Foo", foo.GetFullName())
+	assert.Equal(t, " Foo is a test message\n", string(foo.Comments.Leading))
+
+	name := foo.GetField("name")
+	assert.Equal(t, "n", name.GetStringOption(mojo.E_Alias))
+	assert.True(t, name.Proto.GetOptions().GetDeprecated())
+	assert.Equal(t, " the name\n", string(name.Comments.Trailing))
+
+	bars := foo.GetField("bars")
+	assert.Equal(t, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, bars.Proto.GetLabel())
+	assert.Equal(t, ".mojo.test.Foo.BarsEntry", bars.Proto.GetTypeName())
+	entry := foo.GetMessage("BarsEntry")
+	if assert.NotNil(t, entry) {
+		assert.True(t, entry.Proto.GetOptions().GetMapEntry())
+		assert.Equal(t, ".mojo.test.Foo.Bar", entry.Proto.Field[1].GetTypeName())
+	}
+
+	count := foo.GetField("count")
+	assert.True(t, count.Proto.GetProto3Optional())
+	assert.Equal(t, int32(1), count.Proto.GetOneofIndex())
+	assert.Equal(t, "value", foo.Proto.OneofDecl[0].GetName())
+	assert.Equal(t, "_count", foo.Proto.OneofDecl[1].GetName())
+
+	kind := foo.GetField("kind")
+	assert.Equal(t, descriptorpb.FieldDescriptorProto_TYPE_ENUM, kind.Proto.GetType())
+	assert.Equal(t, ".mojo.test.Kind", kind.Proto.GetTypeName())
+	assert.Equal(t, int32(0), kind.Proto.GetOneofIndex())
+
+	assert.Equal(t, int32(13), foo.Proto.ReservedRange[0].GetEnd())
+	assert.Equal(t, int32(536870912), foo.Proto.ReservedRange[1].GetEnd())
+	assert.Equal(t, []string{"old"}, foo.Proto.ReservedName)
+	assert.False(t, foo.GetMessage("Bar").GetField("values").Proto.GetOptions().GetPacked())
+
+	enum := file.Enums[0]
+	assert.True(t, enum.Proto.Value[1].GetOptions().GetDeprecated())
+	assert.Equal(t, int32(10), enum.Proto.ReservedRange[0].GetEnd())
+
+	service := file.Services[0]
+	get := service.GetMethod("Get")
+	assert.Equal(t, ".mojo.test.Foo.Bar", get.Proto.GetOutputType())
+	watch := service.GetMethod("Watch")
+	assert.True(t, watch.Proto.GetClientStreaming())
+	assert.True(t, watch.Proto.GetServerStreaming())
+	assert.Equal(t, descriptorpb.MethodOptions_NO_SIDE_EFFECTS, watch.Proto.GetOptions().GetIdempotencyLevel())
+
+	_, err = file.ToFileDescriptor()
+	assert.NoError(t, err)
+}
+
+func TestParseFile_SourceCodeInfo(t *testing.T) {
+	file, err := ParseFile("foo.proto", []byte(testProto))
+	assert.NoError(t, err)
+
+	var syntax *descriptorpb.SourceCodeInfo_Location
+	var message *descriptorpb.SourceCodeInfo_Location
+	for _, loc := range file.Proto.GetSourceCodeInfo().GetLocation() {
+		switch {
+		case len(loc.Path) == 1 && loc.Path[0] == 12:
+			syntax = loc
+		case len(loc.Path) == 2 && loc.Path[0] == 4 && loc.Path[1] == 0:
+			message = loc
+		}
+	}
+
+	if assert.NotNil(t, syntax) {
+		assert.Equal(t, []string{" detached\n"}, syntax.LeadingDetachedComments)
+		assert.Equal(t, " leading comment\n", syntax.GetLeadingComments())
+		assert.Equal(t, []int32{3, 0, 18}, syntax.Span)
+	}
+	if assert.NotNil(t, message) {
+		assert.Equal(t, []int32{12, 0, 27, 1}, message.Span)
+	}
+}
+
+func TestParser_ParseFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"protos/base/base.proto": {Data: []byte(`
+syntax = "proto2";
+package base;
+import "google/protobuf/descriptor.proto";
+
+message Base {
+  optional string id = 1 [default = "none"];
+  extensions 100 to 199;
+  optional group Result = 2 {
+    optional int32 code = 1;
+  }
+}
+
+extend google.protobuf.MessageOptions {
+  optional string table = 50001;
+  optional Base base = 50002;
+}
+`)},
+		"protos/app/app.proto": {Data: []byte(`
+syntax = "proto2";
+package app;
+import public "base/base.proto";
+
+message App {
+  option (base.table) = "apps";
+  option (base.base) = { id: "app" Result { code: 1 } };
+  optional base.Base base = 1;
+}
+
+extend base.Base {
+  optional App app = 100;
+}
+`)},
+	}
+
+	packages, err := New(NewFSResolver(fsys, "protos")).ParseFiles("app/app.proto")
+	assert.NoError(t, err)
+	if !assert.NotNil(t, packages) {
+		return
+	}
+
+	base := packages.GetMessage("base.Base")
+	assert.Equal(t, "none", base.GetField("id").Proto.GetDefaultValue())
+	assert.Equal(t, ".base.Base.Result", base.GetField("result").Proto.GetTypeName())
+	assert.Equal(t, descriptorpb.FieldDescriptorProto_TYPE_GROUP, base.GetField("result").Proto.GetType())
+
+	app := packages.GetMessage("app.App")
+	assert.Equal(t, ".base.Base", app.GetField("base").Proto.GetTypeName())
+	assert.Equal(t, base, app.GetField("base").Message)
+	assert.Equal(t, ".base.Base", app.File.Proto.Extension[0].GetExtendee())
+	if extension := packages.GetExtensionByNumber("base.Base", 100); assert.NotNil(t, extension) {
+		assert.Equal(t, base, extension.Extendee)
+		assert.Equal(t, app, extension.Message)
+	}
+	assert.Len(t, packages.GetExtensions("google.protobuf.MessageOptions"), 2)
+
+	data, err := proto.Marshal(app.Proto.GetOptions())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, data)
+
+	_, err = app.File.ToFileDescriptor()
+	assert.NoError(t, err)
+}
+
+func TestParseFile_Errors(t *testing.T) {
+	_, err := ParseFile("foo.proto", []byte("syntax = \"proto3\";\nmessage Foo {\n  int32 foo = 1\n}\n"))
+	if assert.Error(t, err) {
+		assert.Equal(t, "foo.proto:4:1: expected \";\", but found \"}\"", err.Error())
+	}
+
+	_, err = ParseFile("foo.proto", []byte("syntax = \"proto3\";\nmessage Foo {\n  Bar foo = 1;\n}\n"))
+	if assert.Error(t, err) {
+		assert.Equal(t, "foo.proto:3:3: \"Bar\" is not defined", err.Error())
+	}
+
+	_, err = ParseFile("foo.proto", []byte("syntax = \"proto3\";\nimport \"bar.proto\";\n"))
+	assert.Error(t, err)
+}
+
+const commentedProto = `syntax = "proto3";
+
+package foo;
+
+// M is the message
+message M { // the message
+  // the name
+  string s = 1; // single line
+  int32 n = 2;
+  // first line
+  // second line
+
+  // detached
+
+  // the values
+  repeated int32 values = 3;
+}
+
+enum Kind {
+  // two lines
+  // trailing
+
+  KIND_UNSPECIFIED = 0;
+}
+
+service FooService { // the service
+  rpc Get(M) returns (M); // the method
+}
+`
+
+func TestParseFile_PrintComments(t *testing.T) {
+	file, err := ParseFile("foo.proto", []byte(commentedProto))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, file) {
+		return
+	}
+
+	m := file.GetMessage("M")
+	assert.Equal(t, " the message\n", string(m.Comments.Trailing))
+	assert.Equal(t, " first line\n second line\n", string(m.GetField("n").Comments.Trailing))
+
+	printed, err := ParseFile("foo.proto", []byte(file.PrintToString()))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, printed) {
+		return
+	}
+
+	comments := func(locations []*descriptorpb.SourceCodeInfo_Location) map[string]*descriptorpb.SourceCodeInfo_Location {
+		result := make(map[string]*descriptorpb.SourceCodeInfo_Location)
+		for _, loc := range locations {
+			if loc.LeadingComments != nil || loc.TrailingComments != nil || len(loc.LeadingDetachedComments) > 0 {
+				result[fmt.Sprint(loc.Path)] = &descriptorpb.SourceCodeInfo_Location{
+					LeadingComments:         loc.LeadingComments,
+					TrailingComments:        loc.TrailingComments,
+					LeadingDetachedComments: loc.LeadingDetachedComments,
+				}
+			}
+		}
+		return result
+	}
+	expected := comments(file.Proto.GetSourceCodeInfo().GetLocation())
+	actual := comments(printed.Proto.GetSourceCodeInfo().GetLocation())
+	assert.Len(t, expected, 7)
+	for path, loc := range expected {
+		assert.True(t, proto.Equal(loc, actual[path]), "%s: %v != %v", path, loc, actual[path])
+	}
+	assert.Equal(t, len(expected), len(actual))
+}
+
+const editionsProto = `edition = "2023";
+
+package foo;
+
+option features.field_presence = IMPLICIT;
+
+message Foo {
+  option features.(pb.cpp).legacy_closed_enum = true;
+
+  int32 a = 1 [features.field_presence = EXPLICIT];
+  repeated int32 b = 2 [features.repeated_field_encoding = EXPANDED];
+  Foo foo = 3;
+
+  reserved 10 to 12;
+  reserved old, older;
+}
+
+enum Kind {
+  option features.enum_type = CLOSED;
+
+  KIND_UNSPECIFIED = 0;
+}
+`
+
+func TestParseFile_Editions(t *testing.T) {
+	file, err := ParseFile("foo.proto", []byte(editionsProto))
+	assert.NoError(t, err)
+	if !assert.NotNil(t, file) {
+		return
+	}
+
+	assert.Equal(t, descriptor.EditionsSyntax, file.Proto.GetSyntax())
+	assert.Equal(t, "2023", file.Proto.GetEdition())
+	option := file.Proto.GetOptions().GetUninterpretedOption()
+	if assert.Len(t, option, 1) {
+		assert.Equal(t, "IMPLICIT", option[0].GetIdentifierValue())
+	}
+	foo := file.GetMessage("Foo")
+	assert.Equal(t, []string{"old", "older"}, foo.Proto.ReservedName)
+	assert.Len(t, foo.GetField("a").Proto.GetOptions().GetUninterpretedOption(), 1)
+
+	printed, err := ParseFile("foo.proto", []byte(file.PrintToString()))
+	assert.NoError(t, err)
+	if assert.NotNil(t, printed) {
+		file.Proto.SourceCodeInfo = nil
+		printed.Proto.SourceCodeInfo = nil
+		assert.True(t, proto.Equal(file.Proto, printed.Proto), file.PrintToString())
+	}
+}
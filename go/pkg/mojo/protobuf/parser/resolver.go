@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FileResolver opens the .proto file by the import path, it should return an error
+// satisfying errors.Is(err, fs.ErrNotExist) if the file is not found.
+type FileResolver interface {
+	Open(path string) (io.ReadCloser, error)
+}
+
+type FileResolverFunc func(path string) (io.ReadCloser, error)
+
+func (f FileResolverFunc) Open(path string) (io.ReadCloser, error) {
+	return f(path)
+}
+
+// NewFSResolver searches the file in the import paths of the fsys, the root is used if no import path given.
+func NewFSResolver(fsys fs.FS, importPaths ...string) FileResolver {
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	}
+	return FileResolverFunc(func(name string) (io.ReadCloser, error) {
+		for _, importPath := range importPaths {
+			f, err := fsys.Open(path.Join(importPath, name))
+			if err == nil {
+				return f, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	})
+}
+
+// NewDirResolver searches the file in the import directories like the "-I" flag of protoc,
+// the current directory is used if no directory given.
+func NewDirResolver(importPaths ...string) FileResolver {
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	}
+	return FileResolverFunc(func(name string) (io.ReadCloser, error) {
+		for _, importPath := range importPaths {
+			f, err := os.Open(filepath.Join(importPath, filepath.FromSlash(name)))
+			if err == nil {
+				return f, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	})
+}
+
+// overlayResolver resolves the files in memory before the underlying resolver
+type overlayResolver struct {
+	files    map[string][]byte
+	resolver FileResolver
+}
+
+func (r *overlayResolver) Open(name string) (io.ReadCloser, error) {
+	if content, ok := r.files[name]; ok {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	if r.resolver == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return r.resolver.Open(name)
+}
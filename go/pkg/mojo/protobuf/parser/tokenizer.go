@@ -0,0 +1,455 @@
+package parser
+
+import (
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tokenEnd tokenType = iota
+	tokenIdentifier
+	tokenInteger
+	tokenFloat
+	tokenString
+	tokenSymbol
+)
+
+// token is the lexical token with the comments between the previous token and itself,
+// the comments are attributed as what protoc does.
+type token struct {
+	typ  tokenType
+	text string // the raw text of the token
+
+	line      int // zero-based line of the token
+	column    int // zero-based column of the token, the tab advances to the next multiple of 8
+	endColumn int
+
+	prevTrailing string   // the trailing comments of the previous token
+	detached     []string // the detached comments between the previous token and this one
+	leading      string   // the leading comments of this token
+}
+
+type commentType int
+
+const (
+	noComment commentType = iota
+	lineComment
+	blockComment
+	slashNotComment
+)
+
+// commentCollector collects the comments between two tokens, see the CommentCollector in protoc tokenizer.
+type commentCollector struct {
+	buffer          strings.Builder
+	hasComment      bool
+	isLineComment   bool
+	canAttachToPrev bool
+
+	trailing string
+	detached []string
+	leading  string
+}
+
+func (c *commentCollector) bufferForLineComment() *strings.Builder {
+	// if we're already collecting a line comment, keep appending to it
+	if c.hasComment && !c.isLineComment {
+		c.flush()
+	}
+	c.hasComment = true
+	c.isLineComment = true
+	return &c.buffer
+}
+
+func (c *commentCollector) bufferForBlockComment() *strings.Builder {
+	if c.hasComment {
+		c.flush()
+	}
+	c.hasComment = true
+	c.isLineComment = false
+	return &c.buffer
+}
+
+func (c *commentCollector) clearBuffer() {
+	c.buffer.Reset()
+	c.hasComment = false
+}
+
+func (c *commentCollector) flush() {
+	if c.hasComment {
+		if c.canAttachToPrev {
+			c.trailing += c.buffer.String()
+			c.canAttachToPrev = false
+		} else {
+			c.detached = append(c.detached, c.buffer.String())
+		}
+		c.clearBuffer()
+	}
+}
+
+func (c *commentCollector) detachFromPrev() {
+	c.canAttachToPrev = false
+}
+
+// finish takes whatever in the buffer as the leading comments
+func (c *commentCollector) finish() {
+	if c.hasComment {
+		c.leading = c.buffer.String()
+		c.clearBuffer()
+	}
+}
+
+type tokenizer struct {
+	src  string
+	pos  int
+	line int
+	col  int
+
+	err *Error
+}
+
+func newTokenizer(src string) *tokenizer {
+	return &tokenizer{src: src}
+}
+
+// tokenize splits all the source into tokens, the last token is always the tokenEnd
+func tokenize(filename string, src string) ([]*token, error) {
+	t := newTokenizer(src)
+	var tokens []*token
+	for i := 0; ; i++ {
+		tok := t.nextWithComments(i > 0)
+		if t.err != nil {
+			t.err.File = filename
+			return nil, t.err
+		}
+		tokens = append(tokens, tok)
+		if tok.typ == tokenEnd {
+			return tokens, nil
+		}
+	}
+}
+
+func (t *tokenizer) errorf(format string, args ...interface{}) {
+	if t.err == nil {
+		t.err = newError("", t.line, t.col, format, args...)
+	}
+}
+
+func (t *tokenizer) current() byte {
+	if t.pos < len(t.src) {
+		return t.src[t.pos]
+	}
+	return 0
+}
+
+func (t *tokenizer) peek(offset int) byte {
+	if t.pos+offset < len(t.src) {
+		return t.src[t.pos+offset]
+	}
+	return 0
+}
+
+func (t *tokenizer) nextChar() {
+	if t.pos >= len(t.src) {
+		return
+	}
+	switch t.src[t.pos] {
+	case '\n':
+		t.line++
+		t.col = 0
+	case '\t':
+		t.col += 8 - t.col%8
+	default:
+		t.col++
+	}
+	t.pos++
+}
+
+func (t *tokenizer) tryConsume(c byte) bool {
+	if t.pos < len(t.src) && t.src[t.pos] == c {
+		t.nextChar()
+		return true
+	}
+	return false
+}
+
+func isWhitespaceNoNewline(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func (t *tokenizer) consumeWhitespaceNoNewline() {
+	for t.pos < len(t.src) && isWhitespaceNoNewline(t.src[t.pos]) {
+		t.nextChar()
+	}
+}
+
+func (t *tokenizer) tryConsumeCommentStart() commentType {
+	if t.current() == '/' {
+		switch t.peek(1) {
+		case '/':
+			t.nextChar()
+			t.nextChar()
+			return lineComment
+		case '*':
+			t.nextChar()
+			t.nextChar()
+			return blockComment
+		default:
+			return slashNotComment
+		}
+	}
+	return noComment
+}
+
+func (t *tokenizer) consumeLineComment(buffer *strings.Builder) {
+	start := t.pos
+	for t.pos < len(t.src) && t.src[t.pos] != '\n' {
+		t.nextChar()
+	}
+	t.tryConsume('\n')
+	if buffer != nil {
+		buffer.WriteString(t.src[start:t.pos])
+	}
+}
+
+func (t *tokenizer) consumeBlockComment(buffer *strings.Builder) {
+	start := t.pos
+	record := func(end int) {
+		if buffer != nil {
+			buffer.WriteString(t.src[start:end])
+		}
+	}
+
+	for {
+		for c := t.current(); t.pos < len(t.src) && c != '*' && c != '/' && c != '\n'; c = t.current() {
+			t.nextChar()
+		}
+
+		if t.tryConsume('\n') {
+			record(t.pos)
+			// consume the leading whitespace and asterisk
+			t.consumeWhitespaceNoNewline()
+			if t.tryConsume('*') && t.tryConsume('/') {
+				return
+			}
+			start = t.pos
+		} else if t.current() == '*' && t.peek(1) == '/' {
+			record(t.pos)
+			t.nextChar()
+			t.nextChar()
+			return
+		} else if t.current() == '/' && t.peek(1) == '*' {
+			t.errorf("\"/*\" inside block comment, block comments cannot be nested")
+			t.nextChar()
+		} else if t.pos >= len(t.src) {
+			t.errorf("end-of-file inside block comment")
+			return
+		} else {
+			t.nextChar()
+		}
+	}
+}
+
+// nextWithComments reads the next token with the comments before it,
+// which follows the Tokenizer::NextWithComments in protoc.
+func (t *tokenizer) nextWithComments(hasPrev bool) *token {
+	collector := &commentCollector{canAttachToPrev: hasPrev}
+
+	finish := func(tok *token) *token {
+		collector.finish()
+		tok.prevTrailing = collector.trailing
+		tok.detached = collector.detached
+		tok.leading = collector.leading
+		return tok
+	}
+
+	if hasPrev {
+		// a comment appearing on the same line must be attached to the previous declaration
+		t.consumeWhitespaceNoNewline()
+		switch t.tryConsumeCommentStart() {
+		case lineComment:
+			t.consumeLineComment(collector.bufferForLineComment())
+			// don't allow comments on subsequent lines to be attached to a trailing comment
+			collector.flush()
+		case blockComment:
+			t.consumeBlockComment(collector.bufferForBlockComment())
+			t.consumeWhitespaceNoNewline()
+			if !t.tryConsume('\n') {
+				// the next token is on the same line, there is no idea which token the comment should be attached to
+				collector.clearBuffer()
+				return finish(t.next())
+			}
+			collector.flush()
+		case slashNotComment:
+			return finish(t.next())
+		case noComment:
+			if !t.tryConsume('\n') {
+				// the next token is on the same line, there are no comments
+				return finish(t.next())
+			}
+		}
+	}
+
+	// now on the line after the previous token
+	for {
+		if t.err != nil {
+			return &token{}
+		}
+
+		t.consumeWhitespaceNoNewline()
+		switch t.tryConsumeCommentStart() {
+		case lineComment:
+			t.consumeLineComment(collector.bufferForLineComment())
+		case blockComment:
+			t.consumeBlockComment(collector.bufferForBlockComment())
+			// consume the rest of the line so that we don't interpret it as a blank line
+			t.consumeWhitespaceNoNewline()
+			t.tryConsume('\n')
+		case slashNotComment:
+			return finish(t.next())
+		case noComment:
+			if t.tryConsume('\n') {
+				// completely blank line
+				collector.flush()
+				collector.detachFromPrev()
+			} else {
+				tok := t.next()
+				if tok.typ == tokenEnd || tok.text == "}" || tok.text == "]" || tok.text == ")" {
+					// at the end of a scope, it makes no sense to attach a comment to the following token
+					collector.flush()
+				}
+				return finish(tok)
+			}
+		}
+	}
+}
+
+// next reads the next token, skipping the whitespaces and comments
+func (t *tokenizer) next() *token {
+	for t.err == nil {
+		c := t.current()
+		if t.pos >= len(t.src) {
+			return &token{typ: tokenEnd, line: t.line, column: t.col, endColumn: t.col}
+		}
+		if c == '\n' || isWhitespaceNoNewline(c) {
+			t.nextChar()
+			continue
+		}
+		switch t.tryConsumeCommentStart() {
+		case lineComment:
+			t.consumeLineComment(nil)
+			continue
+		case blockComment:
+			t.consumeBlockComment(nil)
+			continue
+		}
+		return t.readToken()
+	}
+	return &token{}
+}
+
+func (t *tokenizer) readToken() *token {
+	tok := &token{line: t.line, column: t.col}
+	start := t.pos
+	c := t.current()
+
+	switch {
+	case isLetter(c):
+		tok.typ = tokenIdentifier
+		for isLetter(t.current()) || isDigit(t.current()) {
+			t.nextChar()
+		}
+	case isDigit(c) || c == '.' && isDigit(t.peek(1)):
+		tok.typ = t.consumeNumber()
+	case c == '"' || c == '\'':
+		tok.typ = tokenString
+		t.consumeString(c)
+	default:
+		if c < 0x20 || c >= 0x7f {
+			t.errorf("invalid control characters encountered in text")
+		}
+		tok.typ = tokenSymbol
+		t.nextChar()
+	}
+
+	tok.text = t.src[start:t.pos]
+	tok.endColumn = t.col
+	return tok
+}
+
+func (t *tokenizer) consumeNumber() tokenType {
+	isFloat := false
+	if t.current() == '0' && (t.peek(1) == 'x' || t.peek(1) == 'X') {
+		t.nextChar()
+		t.nextChar()
+		if !isHexDigit(t.current()) {
+			t.errorf("\"0x\" must be followed by hex digits")
+		}
+		for isHexDigit(t.current()) {
+			t.nextChar()
+		}
+	} else {
+		for isDigit(t.current()) {
+			t.nextChar()
+		}
+		if t.current() == '.' {
+			isFloat = true
+			t.nextChar()
+			for isDigit(t.current()) {
+				t.nextChar()
+			}
+		}
+		if c := t.current(); c == 'e' || c == 'E' {
+			isFloat = true
+			t.nextChar()
+			if c = t.current(); c == '-' || c == '+' {
+				t.nextChar()
+			}
+			if !isDigit(t.current()) {
+				t.errorf("\"e\" must be followed by exponent")
+			}
+			for isDigit(t.current()) {
+				t.nextChar()
+			}
+		}
+	}
+
+	if c := t.current(); isLetter(c) || c == '.' {
+		t.errorf("need space between number and identifier")
+	}
+	if isFloat {
+		return tokenFloat
+	}
+	return tokenInteger
+}
+
+func (t *tokenizer) consumeString(delimiter byte) {
+	t.nextChar()
+	for {
+		switch c := t.current(); {
+		case t.pos >= len(t.src) || c == '\n':
+			t.errorf("string literals cannot cross line boundaries")
+			return
+		case c == '\\':
+			t.nextChar()
+			t.nextChar()
+		case c == delimiter:
+			t.nextChar()
+			return
+		default:
+			t.nextChar()
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package plugin is the runtime of the protoc plugins, which reads the CodeGeneratorRequest into the linked
+// descriptor.Packages, and writes the generated files back in the CodeGeneratorResponse.
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/descriptor"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// FeatureProto3Optional is the feature supported by the plugins built on the descriptor package,
+// which handles the synthetic oneofs of the proto3 optional fields.
+const FeatureProto3Optional = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+type Plugin struct {
+	Request *pluginpb.CodeGeneratorRequest
+
+	// Packages holds all the files in the request, including the imported ones, and is linked
+	Packages *descriptor.Packages
+
+	// Files are the files to generate in the order of the request
+	Files []*descriptor.File
+
+	// Parameters are the key values parsed from the parameter of the request
+	Parameters map[string]string
+
+	// SupportedFeatures are the features reported in the response, FeatureProto3Optional by default
+	SupportedFeatures uint64
+
+	files []*pluginpb.CodeGeneratorResponse_File
+	err   error
+}
+
+// New builds the Plugin from the request, the files in the request are linked into the Packages.
+func New(request *pluginpb.CodeGeneratorRequest) (*Plugin, error) {
+	p := &Plugin{
+		Request:           request,
+		Packages:          descriptor.NewPackages(),
+		Parameters:        ParseParameter(request.GetParameter()),
+		SupportedFeatures: FeatureProto3Optional,
+	}
+
+	for _, fd := range request.GetProtoFile() {
+		p.Packages.AddFile(descriptor.NewFileFrom(fd))
+	}
+	if err := p.Packages.Link(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range request.GetFileToGenerate() {
+		file := p.Packages.FilesByPath[name]
+		if file == nil {
+			return nil, fmt.Errorf("no descriptor for the file to generate %q", name)
+		}
+		p.Files = append(p.Files, file)
+	}
+	return p, nil
+}
+
+// Read reads the CodeGeneratorRequest from the reader and builds the Plugin
+func Read(reader io.Reader) (*Plugin, error) {
+	request, err := readRequest(reader)
+	if err != nil {
+		return nil, err
+	}
+	return New(request)
+}
+
+func readRequest(reader io.Reader) (*pluginpb.CodeGeneratorRequest, error) {
+	input, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the request: %w", err)
+	}
+	request := &pluginpb.CodeGeneratorRequest{}
+	if err = proto.Unmarshal(input, request); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the request: %w", err)
+	}
+	return request, nil
+}
+
+// Run runs the plugin as protoc does, which reads the request from stdin and writes the response to stdout.
+// The errors of linking the request files and returned by the generate function are reported in the response,
+// and the process exits with the status 1 only if the request or the response failed to read or write.
+func Run(generate func(p *Plugin) error) {
+	if err := run(os.Stdin, os.Stdout, generate); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", pluginName(), err)
+		os.Exit(1)
+	}
+}
+
+func run(reader io.Reader, writer io.Writer, generate func(p *Plugin) error) error {
+	request, err := readRequest(reader)
+	if err != nil {
+		return err
+	}
+
+	p, err := New(request)
+	if err != nil {
+		p = &Plugin{Request: request, SupportedFeatures: FeatureProto3Optional}
+		p.Error(err)
+	} else if err = generate(p); err != nil {
+		p.Error(err)
+	}
+	return p.Write(writer)
+}
+
+func pluginName() string {
+	name := os.Args[0]
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// ParseParameter parses the comma separated parameter of the request, like "paths=source_relative,lite",
+// the parameter without the value is parsed as the empty string.
+func ParseParameter(parameter string) map[string]string {
+	parameters := make(map[string]string)
+	for _, param := range strings.Split(parameter, ",") {
+		param = strings.TrimSpace(param)
+		if len(param) == 0 {
+			continue
+		}
+		key, value, _ := strings.Cut(param, "=")
+		parameters[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return parameters
+}
+
+func (p *Plugin) HasParameter(key string) bool {
+	_, ok := p.Parameters[key]
+	return ok
+}
+
+func (p *Plugin) GetParameter(key string) string {
+	return p.Parameters[key]
+}
+
+// GetParameterKeys returns the keys of the parameters in the sorted order
+func (p *Plugin) GetParameterKeys() []string {
+	keys := make([]string, 0, len(p.Parameters))
+	for key := range p.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AddFile adds the generated file, the name is relative to the output directory
+func (p *Plugin) AddFile(name string, content string) *Plugin {
+	p.files = append(p.files, &pluginpb.CodeGeneratorResponse_File{
+		Name:    proto.String(name),
+		Content: proto.String(content),
+	})
+	return p
+}
+
+// AddInsertion inserts the content into the insertion point of the file generated by the other plugins
+// or the earlier generated files in the same response.
+func (p *Plugin) AddInsertion(name string, insertionPoint string, content string) *Plugin {
+	p.files = append(p.files, &pluginpb.CodeGeneratorResponse_File{
+		Name:           proto.String(name),
+		InsertionPoint: proto.String(insertionPoint),
+		Content:        proto.String(content),
+	})
+	return p
+}
+
+// Error reports the error in the response, which means the input proto files are invalid for the plugin
+// and the generated files will be dropped. Only the first error is reported.
+func (p *Plugin) Error(err error) *Plugin {
+	if p.err == nil {
+		p.err = err
+	}
+	return p
+}
+
+func (p *Plugin) Errorf(format string, args ...interface{}) *Plugin {
+	return p.Error(fmt.Errorf(format, args...))
+}
+
+// Response returns the CodeGeneratorResponse with the generated files, or only the error if reported
+func (p *Plugin) Response() *pluginpb.CodeGeneratorResponse {
+	response := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(p.SupportedFeatures),
+	}
+	if p.err != nil {
+		response.Error = proto.String(p.err.Error())
+		return response
+	}
+	response.File = p.files
+	return response
+}
+
+// Write writes the response to the writer
+func (p *Plugin) Write(writer io.Writer) error {
+	output, err := proto.Marshal(p.Response())
+	if err != nil {
+		return fmt.Errorf("failed to marshal the response: %w", err)
+	}
+	if _, err = writer.Write(output); err != nil {
+		return fmt.Errorf("failed to write the response: %w", err)
+	}
+	return nil
+}
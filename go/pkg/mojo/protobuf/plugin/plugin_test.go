@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mojo-lang/protobuf/go/pkg/mojo/protobuf/parser"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const testProto = `syntax = "proto3";
+
+package foo;
+
+message Foo {
+  optional string name = 1;
+  Bar bar = 2;
+}
+
+message Bar {
+  int32 value = 1;
+}
+`
+
+func newTestRequest(t *testing.T) *pluginpb.CodeGeneratorRequest {
+	file, err := parser.ParseFile("foo/foo.proto", []byte(testProto))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/foo.proto"},
+		Parameter:      proto.String("paths=source_relative, lite ,,plugins=grpc=v2"),
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file.Proto},
+	}
+}
+
+func TestParseParameter(t *testing.T) {
+	parameters := ParseParameter("paths=source_relative, lite ,,plugins=grpc=v2")
+	assert.Equal(t, map[string]string{"paths": "source_relative", "lite": "", "plugins": "grpc=v2"}, parameters)
+	assert.Empty(t, ParseParameter(""))
+}
+
+func TestNew(t *testing.T) {
+	p, err := New(newTestRequest(t))
+	if assert.NoError(t, err) && assert.Len(t, p.Files, 1) {
+		foo := p.Files[0].GetMessage("Foo")
+		assert.NotNil(t, foo.GetField("bar").Message)
+		assert.Len(t, foo.GetSyntheticOneofs(), 1)
+	}
+	assert.True(t, p.HasParameter("lite"))
+	assert.Equal(t, "source_relative", p.GetParameter("paths"))
+	assert.Equal(t, []string{"lite", "paths", "plugins"}, p.GetParameterKeys())
+
+	request := newTestRequest(t)
+	request.FileToGenerate = append(request.FileToGenerate, "bar.proto")
+	_, err = New(request)
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	input, err := proto.Marshal(newTestRequest(t))
+	assert.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	err = run(bytes.NewReader(input), output, func(p *Plugin) error {
+		p.AddFile("foo/foo.txt", "// @@protoc_insertion_point(foo)\n")
+		p.AddInsertion("foo/foo.txt", "foo", "Foo\n")
+		return nil
+	})
+	if assert.NoError(t, err) {
+		response := &pluginpb.CodeGeneratorResponse{}
+		assert.NoError(t, proto.Unmarshal(output.Bytes(), response))
+		assert.Equal(t, FeatureProto3Optional, response.GetSupportedFeatures())
+		if assert.Len(t, response.File, 2) {
+			assert.Equal(t, "foo", response.File[1].GetInsertionPoint())
+		}
+	}
+
+	output.Reset()
+	err = run(bytes.NewReader(input), output, func(p *Plugin) error {
+		p.AddFile("foo/foo.txt", "")
+		return errors.New("unsupported file")
+	})
+	if assert.NoError(t, err) {
+		response := &pluginpb.CodeGeneratorResponse{}
+		assert.NoError(t, proto.Unmarshal(output.Bytes(), response))
+		assert.Equal(t, "unsupported file", response.GetError())
+		assert.Empty(t, response.File)
+	}
+	request := newTestRequest(t)
+	request.ProtoFile[0].MessageType[0].Field[1].TypeName = proto.String(".foo.Missing")
+	input, err = proto.Marshal(request)
+	assert.NoError(t, err)
+
+	output.Reset()
+	generated := false
+	err = run(bytes.NewReader(input), output, func(p *Plugin) error {
+		generated = true
+		return nil
+	})
+	if assert.NoError(t, err) {
+		response := &pluginpb.CodeGeneratorResponse{}
+		assert.NoError(t, proto.Unmarshal(output.Bytes(), response))
+		assert.Contains(t, response.GetError(), ".foo.Missing")
+		assert.Equal(t, FeatureProto3Optional, response.GetSupportedFeatures())
+		assert.False(t, generated)
+	}
+
+	assert.Error(t, run(bytes.NewReader([]byte{0xff}), output, func(p *Plugin) error { return nil }))
+}